@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mmcdole/gofeed"
+)
+
+var feedNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func isFeedType(feedType string) bool {
+	return feedType == RSSWebhookType || feedType == TwitterWebhookType || feedType == AlmanaxWebhookType
+}
+
+func isRssFeed(ctx context.Context, url string) bool {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := gofeed.NewParser().ParseURLWithContext(url, ctx)
+	return err == nil
+}
+
+// reloadFeeds starts and stops listeners right away instead of waiting for the next reconcile.
+func reloadFeeds(ctx context.Context) {
+	if feedSupervisor == nil {
+		return
+	}
+
+	if err := feedSupervisor.Reconcile(ctx); err != nil {
+		log.Println("could not reload feeds ", err)
+	}
+}
+
+func writeAdminFeed(w http.ResponseWriter, status int, feed AdminFeedDTO) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(feed); err != nil {
+		http.Error(w, "Error encoding the response.", http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleGetAdminFeeds(w http.ResponseWriter, r *http.Request) {
+	repo := requestRepository(r)
+
+	feeds, err := repo.GetAdminFeeds()
+	if err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(feeds); err != nil {
+		http.Error(w, "Error encoding the response.", http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleCreateAdminFeed(w http.ResponseWriter, r *http.Request) {
+	feedType := chi.URLParam(r, "type")
+	if !isFeedType(feedType) {
+		http.Error(w, "Invalid feed type.", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	var newFeed AdminFeedPost
+	if err = json.NewDecoder(r.Body).Decode(&newFeed); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !feedNameRegex.MatchString(newFeed.Name) {
+		http.Error(w, "Name must only contain letters, digits, '-' and '_'.", http.StatusBadRequest)
+		return
+	}
+
+	switch feedType {
+	case RSSWebhookType:
+		if !isRssFeed(r.Context(), newFeed.Url) {
+			http.Error(w, "Url is not a readable RSS feed.", http.StatusBadRequest)
+			return
+		}
+	case TwitterWebhookType:
+		if newFeed.TwitterId == 0 {
+			http.Error(w, "Twitter id is required.", http.StatusBadRequest)
+			return
+		}
+	case AlmanaxWebhookType:
+		if len(newFeed.Language) != 2 {
+			http.Error(w, "Language must be a two letter code.", http.StatusBadRequest)
+			return
+		}
+		newFeed.Language = strings.ToLower(newFeed.Language)
+	}
+
+	repo := requestRepository(r)
+
+	var id uint64
+	if id, err = repo.CreateFeed(feedType, newFeed); err != nil {
+		if err.Error() == "name already exists" {
+			http.Error(w, "Name already exists.", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	reloadFeeds(r.Context())
+
+	var feed AdminFeedDTO
+	if feed, err = repo.GetAdminFeed(id); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminFeed(w, http.StatusCreated, feed)
+}
+
+// getAdminFeedFromRequest reads the feed from the url. It writes the error response itself if it returns false.
+func getAdminFeedFromRequest(w http.ResponseWriter, r *http.Request, repo Repository) (AdminFeedDTO, bool) {
+	feedType := chi.URLParam(r, "type")
+	if !isFeedType(feedType) {
+		http.Error(w, "Invalid feed type.", http.StatusBadRequest)
+		return AdminFeedDTO{}, false
+	}
+
+	id, err := strconv.ParseUint(r.Context().Value("id").(string), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id.", http.StatusBadRequest)
+		return AdminFeedDTO{}, false
+	}
+
+	feed, err := repo.GetAdminFeed(id)
+	if err != nil {
+		if err.Error() == "not found" {
+			http.Error(w, "Not found.", http.StatusNotFound)
+		} else {
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+		}
+		return AdminFeedDTO{}, false
+	}
+
+	if feed.Type != feedType {
+		http.Error(w, "Not found.", http.StatusNotFound)
+		return AdminFeedDTO{}, false
+	}
+
+	return feed, true
+}
+
+func handleGetAdminFeed(w http.ResponseWriter, r *http.Request) {
+	repo := requestRepository(r)
+
+	feed, ok := getAdminFeedFromRequest(w, r, repo)
+	if !ok {
+		return
+	}
+
+	writeAdminFeed(w, http.StatusOK, feed)
+}
+
+func handlePutAdminFeed(w http.ResponseWriter, r *http.Request) {
+	repo := requestRepository(r)
+
+	feed, ok := getAdminFeedFromRequest(w, r, repo)
+	if !ok {
+		return
+	}
+
+	var err error
+	var update AdminFeedPut
+	if err = json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if update.Name != nil {
+		if !feedNameRegex.MatchString(*update.Name) {
+			http.Error(w, "Name must only contain letters, digits, '-' and '_'.", http.StatusBadRequest)
+			return
+		}
+
+		if err = repo.RenameFeed(feed.Type, feed.Id, *update.Name); err != nil {
+			if err.Error() == "name already exists" {
+				http.Error(w, "Name already exists.", http.StatusConflict)
+				return
+			}
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if update.Disabled != nil {
+		if err = repo.SetFeedDisabled(feed.Id, *update.Disabled); err != nil {
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	reloadFeeds(r.Context())
+
+	if feed, err = repo.GetAdminFeed(feed.Id); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	writeAdminFeed(w, http.StatusOK, feed)
+}
+
+func handleDeleteAdminFeed(w http.ResponseWriter, r *http.Request) {
+	repo := requestRepository(r)
+
+	feed, ok := getAdminFeedFromRequest(w, r, repo)
+	if !ok {
+		return
+	}
+
+	if err := repo.DeleteFeed(feed.Id); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	reloadFeeds(r.Context())
+
+	w.WriteHeader(http.StatusNoContent)
+}
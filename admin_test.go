@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/steinfletcher/apitest"
+	jsonpath "github.com/steinfletcher/apitest-jsonpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AdminTestSuite struct {
+	suite.Suite
+	db       Repository
+	rssFeed  *httptest.Server
+	adminKey string
+	readKey  string
+}
+
+func (suite *AdminTestSuite) SetupSuite() {
+	ReadEnvs()
+
+	pool, err := NewPool(context.Background())
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	suite.db = NewRepository(context.Background(), pool)
+
+	file, err := os.ReadFile("testdata/fusionNewsItem.xml")
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	suite.rssFeed = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write(file)
+	}))
+}
+
+func (suite *AdminTestSuite) TearDownSuite() {
+	suite.rssFeed.Close()
+	suite.db.conn.Close()
+}
+
+func (suite *AdminTestSuite) mintKey(scopes ...string) (string, uuid.UUID) {
+	key, err := generateApiKey()
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	id, err := suite.db.CreateApiKey("test", hashApiKey(key), scopes)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	return key, id
+}
+
+func (suite *AdminTestSuite) SetupTest() {
+	suite.adminKey, _ = suite.mintKey(ScopeAdminRead, ScopeFeedsWrite)
+	suite.readKey, _ = suite.mintKey(ScopeAdminRead)
+}
+
+func (suite *AdminTestSuite) TearDownTest() {
+	if err := testutilCleartables(); err != nil {
+		suite.T().Fatal(err)
+	}
+}
+
+func (suite *AdminTestSuite) Test_Unauthorized() {
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/admin/feeds").
+		Expect(suite.T()).
+		Status(http.StatusUnauthorized).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/admin/feeds").
+		Header("Authorization", "Bearer wrong").
+		Expect(suite.T()).
+		Status(http.StatusUnauthorized).
+		End()
+}
+
+func (suite *AdminTestSuite) Test_Scopes() {
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/admin/feeds").
+		Header("Authorization", "Bearer "+suite.readKey).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/admin/feeds/rss").
+		Header("Authorization", "Bearer "+suite.readKey).
+		JSON(AdminFeedPost{
+			Name: "test-fr-news",
+			Url:  suite.rssFeed.URL,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	key, id := suite.mintKey(ScopeAdminRead)
+	revoked, err := suite.db.RevokeApiKey(id)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), revoked)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/admin/feeds").
+		Header("Authorization", "Bearer "+key).
+		Expect(suite.T()).
+		Status(http.StatusUnauthorized).
+		End()
+}
+
+func (suite *AdminTestSuite) Test_CRUD_Rss() {
+	var created AdminFeedDTO
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/admin/feeds/rss").
+		Header("Authorization", "Bearer "+suite.adminKey).
+		JSON(AdminFeedPost{
+			Name: "test-fr-news",
+			Url:  suite.rssFeed.URL,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Chain().
+			Equal("$.type", "rss").
+			Equal("$.name", "test-fr-news").
+			Equal("$.url", suite.rssFeed.URL).
+			Equal("$.disabled", false).
+			End(),
+		).
+		End().
+		JSON(&created)
+
+	path := "/admin/feeds/rss/" + strconv.FormatUint(created.Id, 10)
+	defer func() {
+		_ = suite.db.DeleteFeed(created.Id)
+	}()
+
+	feeds, err := suite.db.GetRssFeeds([]uint64{created.Id})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), feeds, 1)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/admin/feeds/rss").
+		Header("Authorization", "Bearer "+suite.adminKey).
+		JSON(AdminFeedPost{
+			Name: "test-fr-news",
+			Url:  suite.rssFeed.URL,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusConflict).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/admin/feeds/twitter/"+strconv.FormatUint(created.Id, 10)).
+		Header("Authorization", "Bearer "+suite.adminKey).
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	name := "test-fr-news-renamed"
+	disabled := true
+	apitest.New().
+		Handler(Router(suite.db)).
+		Put(path).
+		Header("Authorization", "Bearer "+suite.adminKey).
+		JSON(AdminFeedPut{
+			Name:     &name,
+			Disabled: &disabled,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Chain().
+			Equal("$.name", name).
+			Equal("$.disabled", true).
+			End(),
+		).
+		End()
+
+	feeds, err = suite.db.GetRssFeeds([]uint64{created.Id})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), feeds, 0)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Delete(path).
+		Header("Authorization", "Bearer "+suite.adminKey).
+		Expect(suite.T()).
+		Status(http.StatusNoContent).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get(path).
+		Header("Authorization", "Bearer "+suite.adminKey).
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (suite *AdminTestSuite) Test_Create_InvalidRss() {
+	notFeed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>no feed here</body></html>"))
+	}))
+	defer notFeed.Close()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/admin/feeds/rss").
+		Header("Authorization", "Bearer "+suite.adminKey).
+		JSON(AdminFeedPost{
+			Name: "test-no-feed",
+			Url:  notFeed.URL,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/admin/feeds/rss").
+		Header("Authorization", "Bearer "+suite.adminKey).
+		JSON(AdminFeedPost{
+			Name: "test feed with spaces",
+			Url:  suite.rssFeed.URL,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		End()
+}
+
+func TestAdminTestSuite(t *testing.T) {
+	suite.Run(t, new(AdminTestSuite))
+}
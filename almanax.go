@@ -27,14 +27,21 @@ func toDTO(webhook AlmanaxWebhook) AlmanaxHookDTO {
 			Timezone:       *webhook.DailySettings.Timezone,
 			MidnightOffset: *webhook.DailySettings.MidnightOffset,
 		},
-		Subscriptions: webhook.Subscriptions,
-		WantsIsoDate:  webhook.WantsIsoDate,
-		Format:        webhook.Format,
-		CreatedAt:     webhook.CreatedAt,
-		UpdatedAt:     webhook.UpdatedAt,
-		LastFiredAt:   webhook.LastFiredAt,
-		WeeklyWeekday: webhook.WeeklyWeekday,
-		Intervals:     webhook.Intervals,
+		Subscriptions:  webhook.Subscriptions,
+		WantsIsoDate:   webhook.WantsIsoDate,
+		Format:         webhook.Format,
+		CreatedAt:      webhook.CreatedAt,
+		UpdatedAt:      webhook.UpdatedAt,
+		LastFiredAt:    webhook.LastFiredAt,
+		WeeklyWeekday:  webhook.WeeklyWeekday,
+		Intervals:      webhook.Intervals,
+		FailureCount:   webhook.FailureCount,
+		DisabledReason: webhook.DisabledReason,
+		DiscordThread:  webhook.DiscordThread,
+		Template:       webhook.Template,
+		Username:       webhook.DiscordUsername,
+		AvatarUrl:      webhook.DiscordAvatarUrl,
+		EmbedColor:     webhook.DiscordEmbedColor,
 	}
 
 	if webhook.BonusWhitelist != nil && len(webhook.BonusWhitelist) > 0 {
@@ -65,12 +72,7 @@ func handleDeleteAlmanaxHook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Internal error.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var hasWebhook bool
 	if hasWebhook, err = repo.HasAlmanaxWebhook(parsedId); err != nil {
@@ -148,6 +150,14 @@ func handleCreateAlmanax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	target := WebhookTarget{
+		Format:   createWebhook.Format,
+		Callback: createWebhook.Callback,
+		Telegram: createWebhook.Telegram,
+		Matrix:   createWebhook.Matrix,
+	}
+	target.Callback = targetCallback(target)
+	createWebhook.Callback = target.Callback
 	if createWebhook.Callback == "" {
 		http.Error(w, "Callback is required.", http.StatusBadRequest)
 		return
@@ -175,13 +185,22 @@ func handleCreateAlmanax(w http.ResponseWriter, r *http.Request) {
 		createWebhook.DailySettings.MidnightOffset = &defaultTzOffset
 	}
 
-	if createWebhook.Format != "discord" {
-		http.Error(w, "Callback must have a known format.", http.StatusBadRequest)
+	if !validateTarget(w, r, target) {
 		return
 	}
 
-	if !isDiscordWebhook(createWebhook.Callback) {
-		http.Error(w, "Callback is not a valid Discord URL.", http.StatusBadRequest)
+	if msg := validateDiscordThread(AlmanaxWebhookType, createWebhook.Format, createWebhook.DiscordThread); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateDiscordTemplate(AlmanaxWebhookType, createWebhook.Format, createWebhook.Template); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateDiscordStyle(createWebhook.Format, DiscordStyle{Username: createWebhook.Username, AvatarUrl: createWebhook.AvatarUrl, EmbedColor: createWebhook.EmbedColor}); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
 
@@ -246,12 +265,7 @@ func handleCreateAlmanax(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Internal error.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var hasAlm bool
 	if hasAlm, err = repo.HasAlmanaxWebhookCallback(createWebhook.Callback); err != nil {
@@ -302,11 +316,24 @@ func handleCreateAlmanax(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	var secret *string
+	if secret, err = newTargetSecret(createWebhook.Format); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
 	var uid uuid.UUID
 	if uid, err = repo.CreateAlmanaxHook(CreateAlmanaxHook{
 		Callback:       createWebhook.Callback,
 		Subscriptions:  createWebhook.Subscriptions,
 		Format:         createWebhook.Format,
+		Telegram:       createWebhook.Telegram,
+		Matrix:         createWebhook.Matrix,
+		DiscordThread:  createWebhook.DiscordThread,
+		Template:       createWebhook.Template,
+		Username:       createWebhook.Username,
+		AvatarUrl:      createWebhook.AvatarUrl,
+		EmbedColor:     createWebhook.EmbedColor,
 		WantsIsoDate:   *createWebhook.WantsIsoDate,
 		DailySettings:  *createWebhook.DailySettings,
 		BonusWhitelist: createWebhook.BonusWhitelist,
@@ -314,6 +341,7 @@ func handleCreateAlmanax(w http.ResponseWriter, r *http.Request) {
 		Mentions:       createWebhook.Mentions,
 		Intervals:      createWebhook.Intervals,
 		WeeklyWeekday:  createWebhook.WeeklyWeekday,
+		Secret:         secret,
 	}); err != nil {
 		if err.Error() == "some feeds not found" {
 			http.Error(w, "Some feeds not found.", http.StatusBadRequest)
@@ -335,9 +363,13 @@ func handleCreateAlmanax(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// the secret is only shown once, on creation
+	hookOut := toDTO(alm)
+	hookOut.Secret = secret
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err = json.NewEncoder(w).Encode(toDTO(alm)); err != nil {
+	if err = json.NewEncoder(w).Encode(hookOut); err != nil {
 		http.Error(w, "Error encoding the response.", http.StatusInternalServerError)
 		return
 	}
@@ -351,6 +383,13 @@ func getAlm(parsedId uuid.UUID, repo Repository) (AlmanaxWebhook, error) {
 		return AlmanaxWebhook{}, err
 	}
 
+	// hooks disabled after failing stay visible, so users can see why
+	if !hasWebhook {
+		if hasWebhook, err = repo.IsDisabledWebhook(AlmanaxWebhookType, parsedId); err != nil {
+			return AlmanaxWebhook{}, err
+		}
+	}
+
 	if !hasWebhook {
 		return AlmanaxWebhook{}, errors.New("not found")
 	}
@@ -385,12 +424,7 @@ func handleGetAlmanax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Internal error.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	hook, err := getAlm(parsedId, repo)
 	if err != nil {
@@ -411,6 +445,24 @@ func handleGetAlmanax(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func handleGetAlmanaxDeliveries(w http.ResponseWriter, r *http.Request) {
+	requestsCRUDTotal.Inc()
+	requestsCRUDAlmanax.Inc()
+	handleGetDeliveries(AlmanaxWebhookType, w, r)
+}
+
+func handleRestoreAlmanax(w http.ResponseWriter, r *http.Request) {
+	requestsCRUDTotal.Inc()
+	requestsCRUDAlmanax.Inc()
+	handleRestoreHook(AlmanaxWebhookType, w, r)
+}
+
+func handleTestAlmanax(w http.ResponseWriter, r *http.Request) {
+	requestsCRUDTotal.Inc()
+	requestsCRUDAlmanax.Inc()
+	handleTestHook(AlmanaxWebhookType, w, r)
+}
+
 func handlePutAlmanax(w http.ResponseWriter, r *http.Request) {
 	requestsCRUDTotal.Inc()
 	requestsCRUDAlmanax.Inc()
@@ -494,12 +546,7 @@ func handlePutAlmanax(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Internal error.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var found bool
 	found, err = repo.HasAlmanaxWebhook(parsedId)
@@ -513,6 +560,30 @@ func handlePutAlmanax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	updateStyle := DiscordStyle{Username: updateHook.Username, AvatarUrl: updateHook.AvatarUrl, EmbedColor: updateHook.EmbedColor}
+	if updateHook.DiscordThread != nil || updateHook.Template != nil || updateStyle != (DiscordStyle{}) {
+		var webhook AlmanaxWebhook
+		if webhook, err = repo.GetAlmanaxHook(parsedId); err != nil {
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+			return
+		}
+
+		if msg := validateDiscordThread(AlmanaxWebhookType, webhook.Format, updateHook.DiscordThread); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		if msg := validateDiscordTemplate(AlmanaxWebhookType, webhook.Format, updateHook.Template); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		if msg := validateDiscordStyle(webhook.Format, updateStyle); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+
 	if err = repo.UpdateAlmanaxHook(updateHook, parsedId); err != nil {
 		if err.Error() == "some feeds not found" {
 			http.Error(w, "Some feeds not found.", http.StatusBadRequest)
@@ -582,26 +653,25 @@ func localTimeFormat(lang string, almDateString string, translations map[string]
 		return "", err
 	}
 
-	var out string
+	layout := localDateLayout(lang)
+	if layout == "" {
+		return "", nil
+	}
+
 	translatedWeekday := translations[lang][parsedAlmTime.Weekday().String()]
-	out += translatedWeekday
+	return translatedWeekday + ", " + parsedAlmTime.Format(layout), nil
+}
 
+// localDateLayout is how dates are written in the given language, empty for unknown languages.
+func localDateLayout(lang string) string {
 	switch lang {
-	case "fr":
-		out += ", " + parsedAlmTime.Format("02/01/2006")
-	case "en":
-		out += ", " + parsedAlmTime.Format("02/01/2006")
 	case "de":
-		out += ", " + parsedAlmTime.Format("02.01.2006")
-	case "es":
-		out += ", " + parsedAlmTime.Format("02/01/2006")
-	case "it":
-		out += ", " + parsedAlmTime.Format("02/01/2006")
+		return "02.01.2006"
+	case "fr", "en", "es", "it":
+		return "02/01/2006"
 	default:
-		return "", nil
+		return ""
 	}
-
-	return out, nil
 }
 
 func getFutureAlmData(almData map[string]dodugo.Almanax, timezone string, daysAhead int) (dodugo.Almanax, error) {
@@ -710,26 +780,8 @@ func buildAlmSpan(tickTime time.Time, intervalType string, tz string, almData ma
 
 // fire hook handlers
 
-func HandleTimeAlmanax(almFeed AlmanaxFeed, _ any, tickTime time.Time, _ time.Duration, repo Repository) ([]AlmanaxSend, error) {
-	var err error
-	if !isNewHour(tickTime) {
-		return nil, nil
-	}
-
-	var subbedWebhooks []AlmanaxWebhook
-	if subbedWebhooks, err = repo.GetAlmanaxSubsForFeed(almFeed); err != nil {
-		return nil, err
-	}
-
-	var atLeastFireOne bool
-	if atLeastFireOne, err = atLeastOneWebhookIsSetToFireNow(subbedWebhooks, tickTime); err != nil {
-		return nil, err
-	}
-
-	if len(subbedWebhooks) == 0 || !atLeastFireOne {
-		return nil, nil
-	}
-
+// fetchAlmanaxData loads the almanax from the day before the given time until a month after it, by date.
+func fetchAlmanaxData(ctx context.Context, language string, from time.Time) (map[string]dodugo.Almanax, error) {
 	parisTz, err := time.LoadLocation("Europe/Paris") // default dofus time
 	if err != nil {
 		return nil, err
@@ -748,8 +800,8 @@ func HandleTimeAlmanax(almFeed AlmanaxFeed, _ any, tickTime time.Time, _ time.Du
 		OperationServers: map[string]dodugo.ServerConfigurations{},
 	}
 	var dodugoClient = dodugo.NewAPIClient(dodugoCfg)
-	options := dodugoClient.AlmanaxAPI.GetAlmanaxRange(context.Background(), almFeed.Language)
-	options = options.Timezone(parisTz.String()).RangeFrom(tickTime.In(parisTz).Add(-24 * time.Hour).Format("2006-01-02")).RangeSize(33)
+	options := dodugoClient.AlmanaxAPI.GetAlmanaxRange(ctx, language)
+	options = options.Timezone(parisTz.String()).RangeFrom(from.In(parisTz).Add(-24 * time.Hour).Format("2006-01-02")).RangeSize(33)
 	almRes, _, err := options.Execute()
 	if err != nil {
 		return nil, err
@@ -760,6 +812,34 @@ func HandleTimeAlmanax(almFeed AlmanaxFeed, _ any, tickTime time.Time, _ time.Du
 		almData[entry.GetDate()] = entry
 	}
 
+	return almData, nil
+}
+
+func HandleTimeAlmanax(almFeed AlmanaxFeed, _ any, tickTime time.Time, _ time.Duration, repo Repository) ([]AlmanaxSend, error) {
+	var err error
+	if !isNewHour(tickTime) {
+		return nil, nil
+	}
+
+	var subbedWebhooks []AlmanaxWebhook
+	if subbedWebhooks, err = repo.GetAlmanaxSubsForFeed(almFeed); err != nil {
+		return nil, err
+	}
+
+	var atLeastFireOne bool
+	if atLeastFireOne, err = atLeastOneWebhookIsSetToFireNow(subbedWebhooks, tickTime); err != nil {
+		return nil, err
+	}
+
+	if len(subbedWebhooks) == 0 || !atLeastFireOne {
+		return nil, nil
+	}
+
+	almData, err := fetchAlmanaxData(context.Background(), almFeed.Language, tickTime)
+	if err != nil {
+		return nil, err
+	}
+
 	var sendWebhooks []IHook
 	var onlyPres []bool
 	var intervals []string
@@ -891,11 +971,63 @@ func formatKamas(kamas int32) string {
 	return string(formatted) + " K"
 }
 
+// almanaxSpanIntro is the line introducing a weekly or monthly almanax overview.
+func almanaxSpanIntro(feedName string, intervalType string) string {
+	if intervalType == "weekly" {
+		switch feedName {
+		case "almanax_fr":
+			return "Voici les bonus de la semaine !"
+		case "almanax_es":
+			return "¡Aquí están los bonos de la semana!"
+		case "almanax_de":
+			return "Hier sind die Boni der Woche!"
+		case "almanax_it":
+			return "Ecco i bonus della settimana!"
+		default:
+			return "Here are the bonuses for the week!"
+		}
+	} else if intervalType == "monthly" {
+		switch feedName {
+		case "almanax_fr":
+			return "Voici les bonus du mois !"
+		case "almanax_es":
+			return "¡Aquí están los bonos del mes!"
+		case "almanax_de":
+			return "Hier sind die Boni des Monats!"
+		case "almanax_it":
+			return "Ecco i bonus del mese!"
+		default:
+			return "Here are the bonuses for the month!"
+		}
+	}
+	return ""
+}
+
+func almanaxTotalLabel(feedName string) string {
+	switch feedName {
+	case "almanax_de":
+		return "Gesamt"
+	case "almanax_it":
+		return "Totale"
+	default:
+		return "Total"
+	}
+}
+
+// almanaxDate formats an almanax date the way the webhook wants it.
+func almanaxDate(almanaxSend AlmanaxSend, webhook IHook, date string) (string, error) {
+	if webhook.IsWantIsoDate() {
+		return date, nil
+	}
+	return localTimeFormat(almanaxSend.Feed.Language, date, almanaxSend.BuildInfo.translations)
+}
+
 func buildDiscordHookAlmanax(almanaxSend AlmanaxSend) ([]PreparedHook, error) {
 	var res []PreparedHook
 	var err error
 	for webhookIdx, webhook := range almanaxSend.Webhooks {
 		var discordWebhook DiscordWebhook
+		var templateData DiscordTemplateData
 		if almanaxSend.IntervalType[webhookIdx] == "daily" {
 			var localAlmData dodugo.Almanax
 			localAlmData, err = getLocalAlmData(almanaxSend.BuildInfo.almData, webhook.GetTimezone())
@@ -1023,6 +1155,9 @@ func buildDiscordHookAlmanax(almanaxSend AlmanaxSend) ([]PreparedHook, error) {
 				}
 			}
 
+			templateData.Mentions = mentionString
+			templateData.Previews = beforeMentions
+
 			discordWebhook.Username = "Almanax"
 			discordWebhook.AvatarUrl = "https://discord.dofusdude.com/almanax_daily.jpg"
 			if almanaxSend.OnlyPreMentions[webhookIdx] {
@@ -1102,34 +1237,7 @@ func buildDiscordHookAlmanax(almanaxSend AlmanaxSend) ([]PreparedHook, error) {
 				}
 			}
 
-			var content string
-			if almanaxSend.IntervalType[webhookIdx] == "weekly" {
-				switch almanaxSend.Feed.GetFeedName() {
-				case "almanax_fr":
-					content = "Voici les bonus de la semaine !"
-				case "almanax_es":
-					content = "¡Aquí están los bonos de la semana!"
-				case "almanax_de":
-					content = "Hier sind die Boni der Woche!"
-				case "almanax_it":
-					content = "Ecco i bonus della settimana!"
-				default:
-					content = "Here are the bonuses for the week!"
-				}
-			} else if almanaxSend.IntervalType[webhookIdx] == "monthly" {
-				switch almanaxSend.Feed.GetFeedName() {
-				case "almanax_fr":
-					content = "Voici les bonus du mois !"
-				case "almanax_es":
-					content = "¡Aquí están los bonos del mes!"
-				case "almanax_de":
-					content = "Hier sind die Boni des Monats!"
-				case "almanax_it":
-					content = "Ecco i bonus del mese!"
-				default:
-					content = "Here are the bonuses for the month!"
-				}
-			}
+			content := almanaxSpanIntro(almanaxSend.Feed.GetFeedName(), almanaxSend.IntervalType[webhookIdx])
 			discordWebhook.Content = &content
 
 			localeWeekSpan := almLocalDateStart + " - " + almLocalDateEnd
@@ -1189,19 +1297,7 @@ func buildDiscordHookAlmanax(almanaxSend AlmanaxSend) ([]PreparedHook, error) {
 				itemsAgg[almItem.GetName()] += tribute.GetQuantity()
 			}
 
-			var totalTranslation string
-			switch almanaxSend.Feed.GetFeedName() {
-			case "almanax_fr":
-				totalTranslation = "Total"
-			case "almanax_es":
-				totalTranslation = "Total"
-			case "almanax_de":
-				totalTranslation = "Gesamt"
-			case "almanax_it":
-				totalTranslation = "Totale"
-			default:
-				totalTranslation = "Total"
-			}
+			totalTranslation := almanaxTotalLabel(almanaxSend.Feed.GetFeedName())
 
 			var totalItems string
 			for itemName, itemQuantity := range itemsAgg {
@@ -1215,20 +1311,103 @@ func buildDiscordHookAlmanax(almanaxSend AlmanaxSend) ([]PreparedHook, error) {
 			})
 		}
 
+		webhook.GetDiscordStyle().apply(&discordWebhook)
+
+		// the hint for upcoming bonuses alone keeps its built-in layout
+		if source := webhook.GetTemplate(); source != nil && !almanaxSend.OnlyPreMentions[webhookIdx] {
+			if discordWebhook, err = renderAlmanaxTemplate(*source, almanaxSend, webhookIdx, templateData, discordWebhook); err != nil {
+				return nil, err
+			}
+		}
+
 		var jsonBody []byte
 		if jsonBody, err = json.Marshal(discordWebhook); err != nil {
 			return nil, err
 		}
 
 		res = append(res, PreparedHook{
-			Callback: webhook.GetCallback(),
-			Body:     string(jsonBody),
+			WebhookId: webhook.GetId(),
+			Callback:  discordThreadCallback(webhook.GetCallback(), webhook.GetDiscordThread()),
+			Body:      string(jsonBody),
 		})
 	}
 
 	return res, nil
 }
 
-func ListenAlmanax(ctx context.Context, feed AlmanaxFeed) {
-	Listen(ctx, AlmanaxPollingRate, feed, nil, HandleTimeAlmanax, buildDiscordHookAlmanax)
+// renderAlmanaxTemplate renders the template of the webhook for its almanax days. Templates that fail on the days are
+// logged and the message falls back to the built-in one, so other webhooks still get theirs.
+func renderAlmanaxTemplate(source string, almanaxSend AlmanaxSend, webhookIdx int, data DiscordTemplateData, fallback DiscordWebhook) (DiscordWebhook, error) {
+	webhook := almanaxSend.Webhooks[webhookIdx]
+
+	var err error
+	if data.Almanax, err = newJsonEventAlmanax(almanaxSend, webhookIdx); err != nil {
+		return DiscordWebhook{}, err
+	}
+	data.Feed = almanaxSend.Feed.GetFeedName()
+
+	rendered, err := renderDiscordTemplate(source, almanaxTemplateDate(almanaxSend, webhook), fallback, data)
+	if err != nil {
+		log.Println("could not render template of webhook", webhook.GetId(), err)
+		return fallback, nil
+	}
+
+	return rendered, nil
+}
+
+// BuildHookAlmanax builds every webhook in the format it was created with.
+func BuildHookAlmanax(almanaxSend AlmanaxSend) ([]PreparedHook, error) {
+	var res []PreparedHook
+
+	discordSend := almanaxSend
+	discordSend.Webhooks = nil
+	discordSend.OnlyPreMentions = nil
+	discordSend.IntervalType = nil
+	for webhookIdx, webhook := range almanaxSend.Webhooks {
+		if webhook.GetFormat() != DiscordFormat && almanaxSend.OnlyPreMentions[webhookIdx] {
+			continue // mentions only exist on Discord, so there is nothing to send
+		}
+
+		switch webhook.GetFormat() {
+		case JsonFormat:
+			hook, err := buildJsonHookAlmanax(almanaxSend, webhookIdx)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case SlackFormat:
+			hook, err := buildSlackHookAlmanax(almanaxSend, webhookIdx)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case TelegramFormat:
+			hook, err := buildTelegramHookAlmanax(almanaxSend, webhookIdx)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case MatrixFormat:
+			hook, err := buildMatrixHookAlmanax(almanaxSend, webhookIdx)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		default:
+			discordSend.Webhooks = append(discordSend.Webhooks, webhook)
+			discordSend.OnlyPreMentions = append(discordSend.OnlyPreMentions, almanaxSend.OnlyPreMentions[webhookIdx])
+			discordSend.IntervalType = append(discordSend.IntervalType, almanaxSend.IntervalType[webhookIdx])
+		}
+	}
+
+	discordHooks, err := buildDiscordHookAlmanax(discordSend)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(res, discordHooks...), nil
+}
+
+func ListenAlmanax(ctx context.Context, repo Repository, feed AlmanaxFeed) {
+	Listen(ctx, repo, AlmanaxPollingRate, feed, nil, HandleTimeAlmanax, BuildHookAlmanax)
 }
@@ -137,13 +137,13 @@ type AlmanaxTestSuite struct {
 func (suite *AlmanaxTestSuite) SetupSuite() {
 	ReadEnvs()
 
-	var repo Repository
-	if err := repo.Init(context.Background()); err != nil {
+	pool, err := NewPool(context.Background())
+	if err != nil {
 		suite.T().Fatal(err)
 	}
 
-	suite.db = repo
-	suite.sut = httptest.NewServer(Router())
+	suite.db = NewRepository(context.Background(), pool)
+	suite.sut = httptest.NewServer(Router(suite.db))
 	suite.discordCheck = append(suite.discordCheck, apitest.NewMock().
 		Get("https://discord.com/api/webhooks/123/abc").
 		RespondWith().
@@ -189,7 +189,7 @@ func (suite *AlmanaxTestSuite) TearDownTest() {
 func (suite *AlmanaxTestSuite) Test_Feeds() {
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/meta/webhooks/almanax").
 		Expect(suite.T()).
 		Status(http.StatusOK).
@@ -218,7 +218,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			BonusBlacklist: nil,
@@ -255,7 +255,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[3]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			BonusBlacklist: []string{
@@ -280,7 +280,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[3]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			BonusBlacklist: []string{
@@ -304,7 +304,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[2]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			BonusBlacklist: []string{},
@@ -327,7 +327,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create() {
 func (suite *AlmanaxTestSuite) Test_CRUD_Create_Defaults() {
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -361,7 +361,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Mentions() {
 	wantIsoDate := true
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -408,7 +408,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Mentions() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[1]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc1",
@@ -445,7 +445,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Mentions() {
 	pingDaysAhead := 5
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[2]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc2",
@@ -485,7 +485,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Mentions() {
 func (suite *AlmanaxTestSuite) Test_CRUD_Delete() {
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -515,7 +515,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Delete() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Delete("/webhooks/almanax/" + uid.String()).
 		Expect(suite.T()).
 		Status(http.StatusNoContent).
@@ -523,7 +523,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Delete() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Delete("/webhooks/almanax/" + uid.String()).
 		Expect(suite.T()).
 		Status(http.StatusNotFound).
@@ -549,7 +549,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_UnknownTz() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(body).
 		Expect(suite.T()).
@@ -563,7 +563,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_LargeOffset() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			BonusBlacklist: nil,
@@ -589,7 +589,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Get() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			BonusBlacklist: nil,
@@ -614,7 +614,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Get() {
 	}
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/webhooks/almanax/" + lastId.String()).
 		Expect(suite.T()).
 		Status(http.StatusOK).
@@ -640,7 +640,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Get() {
 func (suite *AlmanaxTestSuite) Test_CRUD_Create_Intervals_And_Update() {
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -687,7 +687,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Intervals_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			Intervals: []string{
@@ -712,7 +712,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Intervals_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			Intervals: []string{
@@ -726,7 +726,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Intervals_And_Update() {
 	weekday := "monday"
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			WeeklyWeekday: &weekday,
@@ -743,7 +743,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Intervals_And_Update() {
 	unknownWeekday := "foo"
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			WeeklyWeekday: &unknownWeekday,
@@ -756,7 +756,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_Intervals_And_Update() {
 func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -793,7 +793,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			BonusBlacklist: []string{},
@@ -825,7 +825,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			BonusWhitelist: []string{
@@ -855,7 +855,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			BonusWhitelist: []string{
@@ -868,7 +868,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			BonusBlacklist: []string{
@@ -885,7 +885,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			BonusBlacklist: []string{
@@ -901,7 +901,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			BonusBlacklist: []string{
@@ -915,7 +915,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 	putTz1 := "Europe/Berlin1"
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			DailySettings: &WebhookDailySettings{
@@ -930,7 +930,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 	tzOffset := 2
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			DailySettings: &WebhookDailySettings{
@@ -950,7 +950,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 	changeWantsIsoDate := true
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			WantsIsoDate: &changeWantsIsoDate,
@@ -976,7 +976,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 	changeWantsIsoDate = false
 	apitest.New().
 		Mocks(suite.almBonusMock).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/almanax/" + uid.String()).
 		JSON(AlmanaxHookPut{
 			WantsIsoDate: &changeWantsIsoDate,
@@ -993,7 +993,7 @@ func (suite *AlmanaxTestSuite) Test_CRUD_Create_And_Update() {
 func (suite *AlmanaxTestSuite) Test_GetFeeds() {
 	apitest.New().
 		Mocks(suite.almBonusMock, suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/almanax").
 		JSON(AlmanaxHookPost{
 			Callback: "https://discord.com/api/webhooks/123/abc",
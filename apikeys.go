@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeFeedsWrite    = "feeds:write"
+	ScopeTargetsCustom = "targets:custom"
+	ScopeAdminRead     = "admin:read"
+)
+
+const apiKeyPrefix = "adh_"
+
+var apiKeyScopes = []string{ScopeFeedsWrite, ScopeTargetsCustom, ScopeAdminRead}
+
+// generateApiKey returns a new random key. Only its hash is stored, so the key itself can be shown exactly once.
+func generateApiKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseScopes(value string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Split(value, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if !sliceContains(apiKeyScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q, must be one of %s", scope, strings.Join(apiKeyScopes, ", "))
+		}
+		if !sliceContains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	return scopes, nil
+}
+
+// requestApiKey returns the key that authenticated the request, if any.
+func requestApiKey(r *http.Request) (ApiKey, bool) {
+	key, ok := r.Context().Value("api_key").(ApiKey)
+	return key, ok
+}
+
+// authenticateApiKey reads the bearer key of the request. It writes the error response itself if it returns false.
+func authenticateApiKey(w http.ResponseWriter, r *http.Request, token string) (ApiKey, bool) {
+	repo := requestRepository(r)
+	key, err := repo.UseApiKey(hashApiKey(token))
+	if err != nil {
+		if err.Error() == "not found" {
+			http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+		}
+		return ApiKey{}, false
+	}
+
+	return key, true
+}
+
+// requireScope only lets requests through that carry an active API key with the given scope as bearer token.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || token == "" {
+				http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+				return
+			}
+
+			key, ok := authenticateApiKey(w, r, token)
+			if !ok {
+				return
+			}
+
+			if !key.HasScope(scope) {
+				http.Error(w, "Missing scope "+scope+".", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "api_key", key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// optionalApiKey authenticates the request if it carries a bearer key, but lets anonymous requests through.
+func optionalApiKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, ok := authenticateApiKey(w, r, token)
+		if !ok {
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "api_key", key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// runKeysCommand handles the "keys" subcommand to mint, list and revoke API keys.
+func runKeysCommand(repo Repository, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: keys create|list|revoke")
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		name := fs.String("name", "", "Name to recognize the key by.")
+		scopesFlag := fs.String("scopes", "", "Comma separated scopes: "+strings.Join(apiKeyScopes, ", ")+".")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		scopes, err := parseScopes(*scopesFlag)
+		if err != nil {
+			return err
+		}
+
+		key, err := generateApiKey()
+		if err != nil {
+			return err
+		}
+
+		id, err := repo.CreateApiKey(*name, hashApiKey(key), scopes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "id:     %s\nscopes: %s\nkey:    %s\n", id, strings.Join(scopes, ","), key)
+		fmt.Fprintln(out, "The key is not stored and can not be shown again.")
+	case "list":
+		keys, err := repo.GetApiKeys()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "revoked"
+			}
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", key.Id, key.Name, strings.Join(key.Scopes, ","), status)
+		}
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: keys revoke <id>")
+		}
+
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			return errors.New("invalid id")
+		}
+
+		revoked, err := repo.RevokeApiKey(id)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return errors.New("no active key with this id")
+		}
+
+		fmt.Fprintln(out, "revoked", id)
+	default:
+		return fmt.Errorf("unknown keys command %q", args[0])
+	}
+
+	return nil
+}
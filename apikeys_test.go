@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateApiKey(t *testing.T) {
+	key, err := generateApiKey()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+
+	other, err := generateApiKey()
+	assert.Nil(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, hashApiKey(key), hashApiKey(other))
+	assert.Equal(t, hashApiKey(key), hashApiKey(key))
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := parseScopes("feeds:write, admin:read,feeds:write")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{ScopeFeedsWrite, ScopeAdminRead}, scopes)
+
+	_, err = parseScopes("feeds:delete")
+	assert.NotNil(t, err)
+
+	_, err = parseScopes("")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDeliveriesPageSize = 25
+	maxDeliveriesPageSize     = 100
+)
+
+func parsePageQuery(r *http.Request, key string, fallback int) (int, bool) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, true
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+func hasWebhookOfType(webhookType string, id uuid.UUID, repo Repository) (bool, error) {
+	var err error
+	var found bool
+	if webhookType == AlmanaxWebhookType {
+		found, err = repo.HasAlmanaxWebhook(id)
+	} else {
+		found, err = repo.HasSocialWebhook(webhookType, id)
+	}
+	if err != nil || found {
+		return found, err
+	}
+
+	// the deliveries of hooks disabled after failing show what went wrong
+	return repo.IsDisabledWebhook(webhookType, id)
+}
+
+func handleGetDeliveries(webhookType string, w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(w, "Invalid id.", http.StatusBadRequest)
+		return
+	}
+
+	page, ok := parsePageQuery(r, "page", 1)
+	if !ok {
+		http.Error(w, "Invalid page.", http.StatusBadRequest)
+		return
+	}
+
+	pageSize, ok := parsePageQuery(r, "page_size", defaultDeliveriesPageSize)
+	if !ok || pageSize > maxDeliveriesPageSize {
+		http.Error(w, "Invalid page_size, must be between 1 and "+strconv.Itoa(maxDeliveriesPageSize)+".", http.StatusBadRequest)
+		return
+	}
+
+	repo := requestRepository(r)
+
+	var found bool
+	if found, err = hasWebhookOfType(webhookType, parsedId, repo); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Not found.", http.StatusNotFound)
+		return
+	}
+
+	pageOut := DeliveryAttemptsPageDTO{
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if pageOut.Deliveries, pageOut.Total, err = repo.GetDeliveryAttempts(parsedId, pageSize, (page-1)*pageSize); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(pageOut); err != nil {
+		http.Error(w, "Error encoding the response.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// Backoff returns the delay before the given retry (starting at 0). The delay doubles with every retry and is
+// jittered between half and the full value, so hooks failing together don't retry together.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// rateLimitBucket tracks Discord's rate limit of a single webhook. Its mutex queues all requests to the webhook.
+type rateLimitBucket struct {
+	mu        sync.Mutex
+	remaining int
+	resetAt   time.Time
+	// users counts the deliveries holding or waiting for the bucket, guarded by the mutex of the client
+	users int
+}
+
+type WebhookClient struct {
+	client        *http.Client
+	retry         RetryPolicy
+	mu            sync.Mutex
+	buckets       map[string]*rateLimitBucket
+	lastSweep     time.Time
+	globalResetAt time.Time
+}
+
+// maxRateLimitRetries caps how often a single delivery waits for a 429, so a misbehaving target can't block forever.
+const maxRateLimitRetries = 10
+
+var webhookClient = NewWebhookClient(defaultRetryPolicy)
+
+func NewWebhookClient(retry RetryPolicy) *WebhookClient {
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+
+	return &WebhookClient{
+		client:  &http.Client{Timeout: 15 * time.Second},
+		retry:   retry,
+		buckets: make(map[string]*rateLimitBucket),
+	}
+}
+
+// isPermanentDeliveryFailure reports whether the target definitively rejected the webhook, so retrying or keeping the
+// hook makes no sense. That is "Unknown Webhook" or an invalid token on Discord. Slack also answers a revoked token
+// with 403 and an archived channel with 410, other targets can send those for a proxy or auth misconfiguration.
+func isPermanentDeliveryFailure(callback string, statusCode int) bool {
+	if statusCode == http.StatusNotFound || statusCode == http.StatusUnauthorized {
+		return true
+	}
+	return isSlackWebhookUrl(callback) && (statusCode == http.StatusForbidden || statusCode == http.StatusGone)
+}
+
+func isRetryableDeliveryFailure(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError
+}
+
+func rateLimitBucketKey(callback string) string {
+	parsed, err := url.Parse(callback)
+	if err != nil {
+		return callback
+	}
+	// the transaction id of Matrix is unique per message, the limit is per room
+	if path, _, ok := strings.Cut(parsed.Path, "/send/m.room.message/"); ok {
+		return parsed.Host + path
+	}
+	// edits share the limit of their Discord webhook
+	if path, _, ok := strings.Cut(parsed.Path, "/messages/"); ok {
+		return parsed.Host + path
+	}
+	return parsed.Host + parsed.Path
+}
+
+// bucket returns the bucket of the callback for a delivery, which has to release it when done.
+func (c *WebhookClient) bucket(callback string) *rateLimitBucket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now := time.Now(); now.Sub(c.lastSweep) > rateLimitSweepRate {
+		c.sweep(now)
+	}
+
+	key := rateLimitBucketKey(callback)
+	bucket, ok := c.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{remaining: -1}
+		c.buckets[key] = bucket
+	}
+	bucket.users++
+	return bucket
+}
+
+func (c *WebhookClient) release(bucket *rateLimitBucket) {
+	c.mu.Lock()
+	bucket.users--
+	c.mu.Unlock()
+}
+
+// sweep drops the unused buckets whose limit was reset, they are the same as new ones. The caller holds the lock.
+func (c *WebhookClient) sweep(now time.Time) {
+	for key, bucket := range c.buckets {
+		if bucket.users == 0 && !now.Before(bucket.resetAt) {
+			delete(c.buckets, key)
+		}
+	}
+	c.lastSweep = now
+}
+
+// waitForRateLimit blocks until neither the global nor the bucket limit is exhausted. The caller holds the bucket.
+func (c *WebhookClient) waitForRateLimit(ctx context.Context, bucket *rateLimitBucket) error {
+	for {
+		c.mu.Lock()
+		wait := time.Until(c.globalResetAt)
+		c.mu.Unlock()
+
+		if bucket.remaining == 0 {
+			wait = max(wait, time.Until(bucket.resetAt))
+		}
+
+		if wait <= 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func parseRateLimitSeconds(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// rateLimitResponse is the 429 body of Discord, Telegram nests the retry_after in its parameters and Matrix sends
+// milliseconds.
+type rateLimitResponse struct {
+	RetryAfter   float64 `json:"retry_after"`
+	RetryAfterMs int64   `json:"retry_after_ms"`
+	Global       bool    `json:"global"`
+	Parameters   struct {
+		RetryAfter float64 `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// updateRateLimit reads Discord's rate limit headers (and the 429 body) into the bucket or the global limit.
+func (c *WebhookClient) updateRateLimit(bucket *rateLimitBucket, statusCode int, header http.Header, body []byte) {
+	now := time.Now()
+	if remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil {
+		bucket.remaining = remaining
+	}
+	if resetAfter, ok := parseRateLimitSeconds(header.Get("X-RateLimit-Reset-After")); ok {
+		bucket.resetAt = now.Add(resetAfter)
+	}
+
+	if statusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	var limitBody rateLimitResponse
+	_ = json.Unmarshal(body, &limitBody)
+	if limitBody.RetryAfter == 0 {
+		limitBody.RetryAfter = limitBody.Parameters.RetryAfter
+	}
+	if limitBody.RetryAfter == 0 {
+		limitBody.RetryAfter = float64(limitBody.RetryAfterMs) / 1000
+	}
+
+	retryAfter, ok := parseRateLimitSeconds(header.Get("Retry-After"))
+	if !ok {
+		retryAfter = time.Duration(limitBody.RetryAfter * float64(time.Second))
+	}
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+
+	if limitBody.Global || header.Get("X-RateLimit-Global") == "true" || header.Get("X-RateLimit-Scope") == "global" {
+		c.mu.Lock()
+		if resetAt := now.Add(retryAfter); resetAt.After(c.globalResetAt) {
+			c.globalResetAt = resetAt
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	bucket.remaining = 0
+	bucket.resetAt = now.Add(retryAfter)
+}
+
+// Deliver posts the prepared hook, queued behind other requests to the same webhook. It waits for Discord's rate
+// limits and retries network errors and server errors with backoff.
+func (c *WebhookClient) Deliver(ctx context.Context, hook PreparedHook) SendCallbackReturn {
+	res := SendCallbackReturn{
+		Callback: hook.Callback,
+	}
+
+	bucket := c.bucket(hook.Callback)
+	defer c.release(bucket)
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	retries := 0
+	rateLimits := 0
+	for {
+		if res.Err = c.waitForRateLimit(ctx, bucket); res.Err != nil {
+			return res
+		}
+
+		res.Attempts++
+		var header http.Header
+		var body []byte
+		started := time.Now()
+		res.StatusCode, header, body, res.Err = c.post(ctx, hook)
+		res.Latency = time.Since(started)
+		if res.Err != nil && isTelegramApiUrl(hook.Callback) {
+			res.Err = redactTelegramError(res.Err)
+		}
+		if res.Err != nil {
+			log.Println("error posting callback ", res.Err)
+		} else {
+			c.updateRateLimit(bucket, res.StatusCode, header, body)
+
+			if res.StatusCode >= 200 && res.StatusCode < 300 {
+				res.Ok = true
+				if hook.ItemKey != "" {
+					res.Message = parseDiscordMessage(body)
+				}
+				return res
+			}
+
+			if res.StatusCode == http.StatusNotFound && isDiscordMessageUrl(hook.Callback) && discordErrorCode(body) == discordUnknownMessage {
+				// the message to edit was deleted in Discord, so the update is posted as a new one
+				hook.Callback = discordRepostCallback(hook.Callback)
+				continue
+			}
+
+			if isTelegramApiUrl(hook.Callback) {
+				res.Permanent, res.Err = telegramDeliveryError(res.StatusCode, body)
+				if res.Permanent {
+					return res
+				}
+			}
+			if isMatrixSendUrl(hook.Callback) {
+				res.Permanent, res.Err = matrixDeliveryError(res.StatusCode, body)
+				if res.Permanent {
+					return res
+				}
+			}
+
+			if isPermanentDeliveryFailure(hook.Callback, res.StatusCode) {
+				res.Permanent = true
+				return res
+			}
+
+			if res.StatusCode == http.StatusTooManyRequests && rateLimits < maxRateLimitRetries {
+				rateLimits++
+				continue
+			}
+
+			if !isRetryableDeliveryFailure(res.StatusCode) {
+				log.Println("strange return from discord ", res.StatusCode)
+				return res
+			}
+		}
+
+		retries++
+		if retries >= c.retry.MaxAttempts {
+			return res
+		}
+
+		select {
+		case <-time.After(c.retry.Backoff(retries - 1)):
+		case <-ctx.Done():
+			res.Err = ctx.Err()
+			return res
+		}
+	}
+}
+
+// deliveryMethod returns PUT for Matrix, which sends messages with an idempotent transaction, and PATCH for edits of
+// Discord messages. All others get a POST.
+func deliveryMethod(callback string) string {
+	switch {
+	case isMatrixSendUrl(callback):
+		return http.MethodPut
+	case isDiscordMessageUrl(callback):
+		return http.MethodPatch
+	default:
+		return http.MethodPost
+	}
+}
+
+// deliveryUrl is the URL the hook is sent to. Telegram callbacks are stored without the bot token, it is added here.
+func deliveryUrl(hook PreparedHook) string {
+	if isTelegramApiUrl(hook.Callback) && hook.AccessToken != "" {
+		return telegramDeliveryUrl(hook.Callback, hook.AccessToken)
+	}
+	return hook.Callback
+}
+
+// deliveryAuthorization is the Authorization header of the hook, if its target needs one.
+func deliveryAuthorization(hook PreparedHook) string {
+	if hook.AccessToken == "" || isTelegramApiUrl(hook.Callback) {
+		return ""
+	}
+	return "Bearer " + hook.AccessToken
+}
+
+func (c *WebhookClient) post(ctx context.Context, hook PreparedHook) (int, http.Header, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, deliveryMethod(hook.Callback), deliveryUrl(hook), bytes.NewBufferString(hook.Body))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization := deliveryAuthorization(hook); authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if hook.Secret != "" {
+		// signed on every attempt, so retries carry a fresh timestamp
+		for key, value := range signBody(hook.Secret, hook.Body, time.Now()) {
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			log.Println("could not close body io ", err)
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		log.Println("could not read body ", err)
+	}
+
+	return resp.StatusCode, resp.Header, body, nil
+}
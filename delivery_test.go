@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+	}
+
+	for i := 0; i < 20; i++ {
+		delay := policy.Backoff(0)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+
+		delay = policy.Backoff(2)
+		assert.GreaterOrEqual(t, delay, 2*time.Second)
+		assert.LessOrEqual(t, delay, 4*time.Second)
+
+		delay = policy.Backoff(100)
+		assert.GreaterOrEqual(t, delay, 5*time.Second)
+		assert.LessOrEqual(t, delay, 10*time.Second)
+	}
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	res := NewWebhookClient(testRetryPolicy).Deliver(context.Background(), PreparedHook{
+		Callback: server.URL,
+		Body:     "{}",
+	})
+
+	assert.True(t, res.Ok)
+	assert.False(t, res.Permanent)
+	assert.Equal(t, 3, res.Attempts)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+}
+
+func TestDeliverUnknownWebhookIsPermanent(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+	}))
+	defer server.Close()
+
+	res := NewWebhookClient(testRetryPolicy).Deliver(context.Background(), PreparedHook{
+		Callback: server.URL,
+		Body:     "{}",
+	})
+
+	assert.False(t, res.Ok)
+	assert.True(t, res.Permanent)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIsPermanentDeliveryFailure(t *testing.T) {
+	discord := "https://discord.com/api/webhooks/123/abc"
+	slack := "https://hooks.slack.com/services/T000/B000/XXXX"
+
+	assert.True(t, isPermanentDeliveryFailure(discord, http.StatusNotFound))
+	assert.True(t, isPermanentDeliveryFailure(discord, http.StatusUnauthorized))
+	assert.False(t, isPermanentDeliveryFailure(discord, http.StatusForbidden))
+	assert.False(t, isPermanentDeliveryFailure("https://example.com/hooks", http.StatusGone))
+	assert.True(t, isPermanentDeliveryFailure(slack, http.StatusForbidden))
+	assert.True(t, isPermanentDeliveryFailure(slack, http.StatusGone))
+	assert.False(t, isPermanentDeliveryFailure(slack, http.StatusInternalServerError))
+}
+
+func TestDeliverNetworkErrorIsNotPermanent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	res := NewWebhookClient(testRetryPolicy).Deliver(context.Background(), PreparedHook{
+		Callback: server.URL,
+		Body:     "{}",
+	})
+
+	assert.False(t, res.Ok)
+	assert.False(t, res.Permanent)
+	assert.NotNil(t, res.Err)
+	assert.Equal(t, testRetryPolicy.MaxAttempts, res.Attempts)
+}
+
+func TestDeliverBadRequestIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	res := NewWebhookClient(testRetryPolicy).Deliver(context.Background(), PreparedHook{
+		Callback: server.URL,
+		Body:     "{}",
+	})
+
+	assert.False(t, res.Ok)
+	assert.False(t, res.Permanent)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDeliverHonorsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0.1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.1, "global": false}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// rate limits don't use up the retry attempts
+	client := NewWebhookClient(RetryPolicy{MaxAttempts: 1})
+	start := time.Now()
+	res := client.Deliver(context.Background(), PreparedHook{
+		Callback: server.URL,
+		Body:     "{}",
+	})
+
+	assert.True(t, res.Ok)
+	assert.Equal(t, 2, res.Attempts)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestDeliverWaitsForExhaustedBucket(t *testing.T) {
+	var requestTimes []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestTimes = append(requestTimes, time.Now())
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset-After", "0.1")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewWebhookClient(testRetryPolicy)
+	for i := 0; i < 2; i++ {
+		res := client.Deliver(context.Background(), PreparedHook{
+			Callback: server.URL + "/api/webhooks/123/abc",
+			Body:     "{}",
+		})
+		assert.True(t, res.Ok)
+	}
+
+	assert.Len(t, requestTimes, 2)
+	assert.GreaterOrEqual(t, requestTimes[1].Sub(requestTimes[0]), 100*time.Millisecond)
+}
+
+func TestUpdateRateLimitGlobal(t *testing.T) {
+	client := NewWebhookClient(testRetryPolicy)
+	bucket := client.bucket("https://discord.com/api/webhooks/123/abc")
+
+	header := http.Header{}
+	header.Set("X-RateLimit-Global", "true")
+	client.updateRateLimit(bucket, http.StatusTooManyRequests, header, []byte(`{"retry_after": 2.5, "global": true}`))
+
+	assert.Equal(t, -1, bucket.remaining)
+	assert.WithinDuration(t, time.Now().Add(2500*time.Millisecond), client.globalResetAt, 100*time.Millisecond)
+
+	// other webhooks wait for the global limit too
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.waitForRateLimit(ctx, client.bucket("https://discord.com/api/webhooks/456/def")), context.DeadlineExceeded)
+}
+
+func TestWebhookClientSweepsBuckets(t *testing.T) {
+	client := NewWebhookClient(testRetryPolicy)
+	now := time.Now()
+
+	reset := client.bucket("https://discord.com/api/webhooks/1/a")
+	client.release(reset)
+	exhausted := client.bucket("https://discord.com/api/webhooks/2/b")
+	exhausted.remaining = 0
+	exhausted.resetAt = now.Add(time.Minute)
+	client.release(exhausted)
+	// still held by a delivery
+	client.bucket("https://discord.com/api/webhooks/3/c")
+
+	client.mu.Lock()
+	client.sweep(now)
+	client.mu.Unlock()
+
+	assert.Len(t, client.buckets, 2)
+	assert.NotContains(t, client.buckets, "discord.com/api/webhooks/1/a")
+	assert.Same(t, exhausted, client.buckets["discord.com/api/webhooks/2/b"])
+
+	// deliveries release their bucket when done
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	assert.True(t, client.Deliver(context.Background(), PreparedHook{Callback: server.URL + "/api/webhooks/4/d", Body: "{}"}).Ok)
+	client.mu.Lock()
+	client.sweep(time.Now())
+	client.mu.Unlock()
+	assert.Len(t, client.buckets, 2)
+	assert.NotContains(t, client.buckets, rateLimitBucketKey(server.URL+"/api/webhooks/4/d"))
+}
+
+func TestRateLimitBucketKey(t *testing.T) {
+	assert.Equal(t, "discord.com/api/webhooks/123/abc", rateLimitBucketKey("https://discord.com/api/webhooks/123/abc?wait=true"))
+}
+
+func TestDeliverEditsDiscordMessage(t *testing.T) {
+	var methods []string
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		paths = append(paths, r.URL.RequestURI())
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Unknown Message", "code": 10008}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "1300", "channel_id": "1100"}`))
+	}))
+	defer server.Close()
+
+	res := NewWebhookClient(testRetryPolicy).Deliver(context.Background(), PreparedHook{
+		Callback: server.URL + "/api/webhooks/1/abc/messages/1200?thread_id=1100",
+		Body:     "{}",
+		ItemKey:  "guid:1",
+	})
+
+	// the deleted message is posted again instead of disabling the webhook
+	assert.True(t, res.Ok)
+	assert.Equal(t, []string{http.MethodPatch, http.MethodPost}, methods)
+	assert.Equal(t, []string{"/api/webhooks/1/abc/messages/1200?thread_id=1100", "/api/webhooks/1/abc?thread_id=1100&wait=true"}, paths)
+	assert.Equal(t, &DiscordMessageRef{MessageId: "1300", ChannelId: "1100"}, res.Message)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// discordThreadNameLimit is the maximum length of a forum post title.
+const discordThreadNameLimit = 100
+
+// discordUsernameLimit is the maximum length of a webhook username.
+const discordUsernameLimit = 80
+
+// discordMaxColor is the largest embed color, white.
+const discordMaxColor = 0xFFFFFF
+
+// discordUnknownMessage is the error code of Discord when the message to edit was deleted.
+const discordUnknownMessage = 10008
+
+var discordSnowflakeRegex = regexp.MustCompile(`^\d{1,20}$`)
+
+// rssForumTagKinds are the kinds of RSS feeds that can be mapped to forum tags, taken from the end of the feed name.
+var rssForumTagKinds = []string{"news", "changelog", "devblog"}
+
+// isEmpty reports whether the thread settings post to the channel itself, like without settings.
+func (t *DiscordThread) isEmpty() bool {
+	return t == nil || (t.ThreadId == nil && !t.Forum && len(t.ForumTags) == 0)
+}
+
+// normalizeDiscordThread returns nil for settings without any effect, so they are stored as null.
+func normalizeDiscordThread(thread *DiscordThread) *DiscordThread {
+	if thread.isEmpty() {
+		return nil
+	}
+	return thread
+}
+
+// validateDiscordThread checks the thread settings of a webhook and returns the error message if they are not valid.
+func validateDiscordThread(webhookType string, format string, thread *DiscordThread) string {
+	if thread.isEmpty() {
+		return ""
+	}
+
+	if format != DiscordFormat {
+		return "Threads are only supported for Discord webhooks."
+	}
+
+	if thread.ThreadId != nil {
+		if thread.Forum {
+			return "A webhook can either post into a thread or create forum posts, not both."
+		}
+		if !discordSnowflakeRegex.MatchString(*thread.ThreadId) {
+			return "Thread id must be a Discord id."
+		}
+	}
+
+	if (thread.Forum || len(thread.ForumTags) > 0) && webhookType != RSSWebhookType {
+		return "Forum posts are only supported for RSS webhooks."
+	}
+
+	if len(thread.ForumTags) > 0 && !thread.Forum {
+		return "Forum tags need forum posts."
+	}
+
+	for kind, tagId := range thread.ForumTags {
+		if !sliceContains(rssForumTagKinds, kind) {
+			return "Forum tags must be one of " + strings.Join(rssForumTagKinds, ", ") + "."
+		}
+		if !discordSnowflakeRegex.MatchString(tagId) {
+			return "Forum tag of " + kind + " must be a Discord id."
+		}
+	}
+
+	return ""
+}
+
+// validateDiscordStyle checks the custom username, avatar and embed color of a webhook and returns the error message
+// if they are not valid. Empty texts and negative colors reset to the built-in ones.
+func validateDiscordStyle(format string, style DiscordStyle) string {
+	if isEmptyText(style.Username) && isEmptyText(style.AvatarUrl) && isDefaultColor(style.EmbedColor) {
+		return ""
+	}
+
+	if format != DiscordFormat {
+		return "Username, avatar and embed color are only supported for Discord webhooks."
+	}
+
+	if style.Username != nil {
+		if utf8.RuneCountInString(*style.Username) > discordUsernameLimit {
+			return fmt.Sprintf("Username must not be longer than %d characters.", discordUsernameLimit)
+		}
+		// Discord refuses webhook usernames pretending to be Discord itself
+		lowerUsername := strings.ToLower(*style.Username)
+		if strings.Contains(lowerUsername, "discord") || strings.Contains(lowerUsername, "clyde") {
+			return "Username must not contain 'discord' or 'clyde'."
+		}
+	}
+
+	if !isEmptyText(style.AvatarUrl) && !isHttpsUrl(*style.AvatarUrl) {
+		return "Avatar url must be an https url."
+	}
+
+	if style.EmbedColor != nil && *style.EmbedColor > discordMaxColor {
+		return fmt.Sprintf("Embed color must be between 0 and %d (0xFFFFFF), or negative for the built-in one.", discordMaxColor)
+	}
+
+	return ""
+}
+
+// apply replaces the built-in username, avatar and embed color of the message with the custom ones.
+func (s DiscordStyle) apply(discordWebhook *DiscordWebhook) {
+	if !isEmptyText(s.Username) {
+		discordWebhook.Username = *s.Username
+	}
+	if !isEmptyText(s.AvatarUrl) {
+		discordWebhook.AvatarUrl = *s.AvatarUrl
+	}
+	if s.EmbedColor != nil {
+		for i := range discordWebhook.Embeds {
+			discordWebhook.Embeds[i].Color = *s.EmbedColor
+		}
+	}
+}
+
+func setCallbackQuery(callback string, key string, value string) string {
+	parsed, err := url.Parse(callback)
+	if err != nil {
+		return callback
+	}
+	query := parsed.Query()
+	query.Set(key, value)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// discordThreadCallback adds the thread to the webhook URL, Discord only reads thread_id from the query.
+func discordThreadCallback(callback string, thread *DiscordThread) string {
+	if thread == nil || thread.ThreadId == nil {
+		return callback
+	}
+	return setCallbackQuery(callback, "thread_id", *thread.ThreadId)
+}
+
+// discordWaitCallback makes Discord respond with the created message, so its id can be stored.
+func discordWaitCallback(callback string) string {
+	return setCallbackQuery(callback, "wait", "true")
+}
+
+// discordMessageCallback returns the URL to edit a message of the webhook. Messages in threads and forum posts can
+// only be found with the thread they are in.
+func discordMessageCallback(callback string, thread *DiscordThread, message DiscordMessageRef) string {
+	parsed, err := url.Parse(callback)
+	if err != nil {
+		return callback
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + "/messages/" + message.MessageId
+	parsed.RawQuery = ""
+	if !thread.isEmpty() {
+		parsed.RawQuery = url.Values{"thread_id": {message.ChannelId}}.Encode()
+	}
+	return parsed.String()
+}
+
+func isDiscordMessageUrl(callback string) bool {
+	return strings.Contains(callback, "/api/webhooks/") && strings.Contains(callback, "/messages/")
+}
+
+// discordRepostCallback turns the URL to edit a message back into the URL to post a new one, keeping the thread.
+func discordRepostCallback(callback string) string {
+	parsed, err := url.Parse(callback)
+	if err != nil {
+		return callback
+	}
+	parsed.Path, _, _ = strings.Cut(parsed.Path, "/messages/")
+	return discordWaitCallback(parsed.String())
+}
+
+// parseDiscordMessage reads the message Discord returns for requests with wait=true.
+func parseDiscordMessage(body []byte) *DiscordMessageRef {
+	var message DiscordMessageRef
+	if err := json.Unmarshal(body, &message); err != nil || message.MessageId == "" {
+		return nil
+	}
+	return &message
+}
+
+func discordErrorCode(body []byte) int {
+	var response struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(body, &response)
+	return response.Code
+}
+
+func rssFeedKind(feed IFeed) string {
+	nameParts := strings.Split(feed.GetFeedName(), "-")
+	return strings.ToLower(nameParts[len(nameParts)-1])
+}
+
+// applyDiscordForumPost makes the message create a new forum post named after the item, tagged by the kind of feed.
+func applyDiscordForumPost(discordWebhook *DiscordWebhook, thread *DiscordThread, title string, feed IFeed) {
+	discordWebhook.ThreadName = ""
+	discordWebhook.AppliedTags = nil
+	if thread == nil || !thread.Forum {
+		return
+	}
+
+	discordWebhook.ThreadName = TruncateText(title, discordThreadNameLimit-len(" ..."))
+	if tagId, ok := thread.ForumTags[rssFeedKind(feed)]; ok {
+		discordWebhook.AppliedTags = []string{tagId}
+	}
+}
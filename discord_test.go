@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDiscordThread(t *testing.T) {
+	threadId := "1180000000000000000"
+	invalidThreadId := "general"
+
+	assert.Equal(t, "", validateDiscordThread(AlmanaxWebhookType, DiscordFormat, nil))
+	assert.Equal(t, "", validateDiscordThread(AlmanaxWebhookType, SlackFormat, &DiscordThread{}))
+	assert.Equal(t, "", validateDiscordThread(AlmanaxWebhookType, DiscordFormat, &DiscordThread{ThreadId: &threadId}))
+	assert.Equal(t, "", validateDiscordThread(RSSWebhookType, DiscordFormat, &DiscordThread{Forum: true, ForumTags: map[string]string{"devblog": "42"}}))
+
+	assert.Equal(t, "Threads are only supported for Discord webhooks.", validateDiscordThread(RSSWebhookType, SlackFormat, &DiscordThread{ThreadId: &threadId}))
+	assert.Equal(t, "Thread id must be a Discord id.", validateDiscordThread(RSSWebhookType, DiscordFormat, &DiscordThread{ThreadId: &invalidThreadId}))
+	assert.Equal(t, "A webhook can either post into a thread or create forum posts, not both.", validateDiscordThread(RSSWebhookType, DiscordFormat, &DiscordThread{ThreadId: &threadId, Forum: true}))
+	assert.Equal(t, "Forum posts are only supported for RSS webhooks.", validateDiscordThread(TwitterWebhookType, DiscordFormat, &DiscordThread{Forum: true}))
+	assert.Equal(t, "Forum tags need forum posts.", validateDiscordThread(RSSWebhookType, DiscordFormat, &DiscordThread{ForumTags: map[string]string{"news": "42"}}))
+	assert.Equal(t, "Forum tags must be one of news, changelog, devblog.", validateDiscordThread(RSSWebhookType, DiscordFormat, &DiscordThread{Forum: true, ForumTags: map[string]string{"patch": "42"}}))
+	assert.Equal(t, "Forum tag of news must be a Discord id.", validateDiscordThread(RSSWebhookType, DiscordFormat, &DiscordThread{Forum: true, ForumTags: map[string]string{"news": "breaking"}}))
+}
+
+func TestDiscordThreadCallback(t *testing.T) {
+	threadId := "1180000000000000000"
+	callback := "https://discord.com/api/webhooks/123/abc"
+
+	assert.Equal(t, callback, discordThreadCallback(callback, nil))
+	assert.Equal(t, callback, discordThreadCallback(callback, &DiscordThread{Forum: true}))
+	assert.Equal(t, callback+"?thread_id=1180000000000000000", discordThreadCallback(callback, &DiscordThread{ThreadId: &threadId}))
+}
+
+func TestBuildDiscordHookRssThreads(t *testing.T) {
+	threadId := "1180000000000000000"
+	forumHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/forum",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+		DiscordThread: &DiscordThread{Forum: true, ForumTags: map[string]string{"devblog": "42", "news": "43"}},
+	}
+	threadHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/thread",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+		DiscordThread: &DiscordThread{ThreadId: &threadId},
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title:       "Fusion des serveurs",
+			Link:        "https://www.dofus.com/fr/devblog/1",
+			Description: "<p>Les serveurs fusionnent.</p>",
+		},
+		Webhooks: []IHook{forumHook, threadHook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-devblog"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 2)
+
+	var forumMessage DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &forumMessage))
+	assert.Equal(t, forumHook.Callback, hooks[0].Callback)
+	assert.Equal(t, "Fusion des serveurs", forumMessage.ThreadName)
+	assert.Equal(t, []string{"42"}, forumMessage.AppliedTags)
+
+	var threadMessage DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[1].Body), &threadMessage))
+	assert.Equal(t, threadHook.Callback+"?thread_id=1180000000000000000", hooks[1].Callback)
+	assert.Empty(t, threadMessage.ThreadName)
+	assert.Empty(t, threadMessage.AppliedTags)
+}
+
+func TestBuildDiscordHookRssEditsPostedMessages(t *testing.T) {
+	postedHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/posted",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+		DiscordThread: &DiscordThread{Forum: true},
+	}
+	newHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/new",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item:     gofeed.Item{Title: "Changelog 3.1", Description: "<p>Fixed.</p>"},
+		ItemKey:  "guid:1",
+		Updated:  true,
+		Webhooks: []IHook{postedHook, newHook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-en-official-changelog"},
+		Messages: map[uuid.UUID]DiscordMessageRef{postedHook.Id: {MessageId: "1200", ChannelId: "1100"}},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 2)
+
+	var edit DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &edit))
+	assert.Equal(t, "https://discord.com/api/webhooks/123/posted/messages/1200?thread_id=1100", hooks[0].Callback)
+	assert.Equal(t, http.MethodPatch, deliveryMethod(hooks[0].Callback))
+	assert.Equal(t, "Changelog 3.1", *edit.Embeds[0].Title)
+	assert.Empty(t, edit.ThreadName)
+	assert.Equal(t, "guid:1", hooks[0].ItemKey)
+
+	var post DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[1].Body), &post))
+	assert.Equal(t, "https://discord.com/api/webhooks/123/new?wait=true", hooks[1].Callback)
+	assert.Equal(t, http.MethodPost, deliveryMethod(hooks[1].Callback))
+	assert.Equal(t, "[Update] Changelog 3.1", *post.Embeds[0].Title)
+}
+
+func TestValidateDiscordStyle(t *testing.T) {
+	username := "Dofus News"
+	empty := ""
+	avatarUrl := "https://example.com/avatar.png"
+	httpAvatarUrl := "http://example.com/avatar.png"
+	discordUsername := "Discord Bot"
+	longUsername := strings.Repeat("a", discordUsernameLimit+1)
+	color := 0xFFFFFF
+	negativeColor := -1
+	tooLargeColor := 0x1000000
+
+	assert.Equal(t, "", validateDiscordStyle(DiscordFormat, DiscordStyle{}))
+	assert.Equal(t, "", validateDiscordStyle(SlackFormat, DiscordStyle{Username: &empty, AvatarUrl: &empty}))
+	assert.Equal(t, "", validateDiscordStyle(DiscordFormat, DiscordStyle{Username: &username, AvatarUrl: &avatarUrl, EmbedColor: &color}))
+
+	assert.Equal(t, "Username, avatar and embed color are only supported for Discord webhooks.", validateDiscordStyle(JsonFormat, DiscordStyle{EmbedColor: &color}))
+	assert.Equal(t, "Username must not be longer than 80 characters.", validateDiscordStyle(DiscordFormat, DiscordStyle{Username: &longUsername}))
+	assert.Equal(t, "Username must not contain 'discord' or 'clyde'.", validateDiscordStyle(DiscordFormat, DiscordStyle{Username: &discordUsername}))
+	assert.Equal(t, "Avatar url must be an https url.", validateDiscordStyle(DiscordFormat, DiscordStyle{AvatarUrl: &httpAvatarUrl}))
+	assert.Equal(t, "", validateDiscordStyle(DiscordFormat, DiscordStyle{EmbedColor: &negativeColor}))
+	assert.Equal(t, "", validateDiscordStyle(JsonFormat, DiscordStyle{EmbedColor: &negativeColor}))
+	assert.Equal(t, "Embed color must be between 0 and 16777215 (0xFFFFFF), or negative for the built-in one.", validateDiscordStyle(DiscordFormat, DiscordStyle{EmbedColor: &tooLargeColor}))
+}
+
+func TestNullableColor(t *testing.T) {
+	color := 0xFF0000
+	black := 0
+	negativeColor := -1
+
+	assert.Nil(t, nullableColor(nil))
+	assert.Nil(t, nullableColor(&negativeColor))
+	assert.Equal(t, &color, nullableColor(&color))
+	assert.Equal(t, &black, nullableColor(&black))
+}
+
+func TestBuildDiscordHooksStyle(t *testing.T) {
+	username := "Dofus News"
+	avatarUrl := "https://example.com/avatar.png"
+	color := 0xFF0000
+	rssHook := RssWebhook{
+		Id:                uuid.New(),
+		Callback:          "https://discord.com/api/webhooks/123/rss",
+		Format:            DiscordFormat,
+		PreviewLength:     2000,
+		DiscordUsername:   &username,
+		DiscordAvatarUrl:  &avatarUrl,
+		DiscordEmbedColor: &color,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item:     gofeed.Item{Title: "Maintenance", Link: "https://www.dofus.com/fr/news/1"},
+		Webhooks: []IHook{rssHook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, username, message.Username)
+	assert.Equal(t, avatarUrl, message.AvatarUrl)
+	assert.Equal(t, color, message.Embeds[0].Color)
+
+	tz := "UTC"
+	almanaxHook := AlmanaxWebhook{
+		Id:                uuid.New(),
+		Callback:          "https://discord.com/api/webhooks/123/almanax",
+		Format:            DiscordFormat,
+		WantsIsoDate:      true,
+		DailySettings:     WebhookDailySettings{Timezone: &tz},
+		DiscordEmbedColor: &color,
+	}
+
+	hooks, err = BuildHookAlmanax(testAlmanaxSend(almanaxHook, "weekly"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	message = DiscordWebhook{}
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "Almanax", message.Username)
+	assert.Equal(t, "https://discord.dofusdude.com/almanax_daily.jpg", message.AvatarUrl)
+	for _, embed := range message.Embeds {
+		assert.Equal(t, color, embed.Color)
+	}
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"io"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -30,104 +28,105 @@ func isDiscordWebhook(url string) bool {
 	return getDiscordHook.StatusCode == http.StatusOK
 }
 
-func tick[CustomObj any, Feed IFeed, State any](ctx context.Context, tickTime time.Time, state State,
+// pendingFeedState is implemented by listener states that only advance once the deliveries of a tick are enqueued.
+type pendingFeedState interface {
+	pendingFeedState() *FeedStateUpdate
+	commitFeedState()
+}
+
+func tick[CustomObj any, Feed IFeed, State any](ctx context.Context, repo Repository, tickTime time.Time, state State,
 	feed Feed,
 	tickRate time.Duration,
 	handleTime func(socialFeed Feed, state State, tickTime time.Time, tickRate time.Duration, repo Repository) ([]CustomObj, error),
 	buildDiscordWebhook func(customObj CustomObj) ([]PreparedHook, error)) error {
 	var err error
-	var repo Repository
-	if err = repo.Init(ctx); err != nil {
-		return err
-	}
-	defer repo.Deinit()
+	repo = repo.WithContext(ctx)
 
 	topicSends, err := handleTime(feed, state, tickTime, tickRate, repo)
 	if err != nil {
 		return err
 	}
 
-	if topicSends == nil {
+	var stateUpdate *FeedStateUpdate
+	pendingState, hasPendingState := any(state).(pendingFeedState)
+	if hasPendingState {
+		stateUpdate = pendingState.pendingFeedState()
+	}
+
+	var preparedHooks []PreparedHook
+	for _, topicSend := range topicSends {
+		var topicHooks []PreparedHook
+		if topicHooks, err = buildDiscordWebhook(topicSend); err != nil {
+			log.Println("Error while buildDiscordWebhook in feed ", feed.GetFeedName(), err)
+			continue
+		}
+		preparedHooks = append(preparedHooks, topicHooks...)
+	}
+
+	if len(preparedHooks) == 0 && stateUpdate == nil {
 		return nil
 	}
 
-	callbackReturns := make(chan SendCallbackReturn)
-	for _, webhook := range topicSends {
-		go func(topicHooks CustomObj, res chan SendCallbackReturn) {
-			var preparedHooks []PreparedHook
-			preparedHooks, err = buildDiscordWebhook(topicHooks)
-			if err != nil {
-				log.Println("Error while buildDiscordWebhook in feed ", feed.GetFeedName(), err)
-				res <- SendCallbackReturn{
-					Callback: "",
-					Ok:       true,
-				} // TODO handle more verbose
-				return
-			}
+	for i := range preparedHooks {
+		preparedHooks[i].FeedId = feed.GetId()
+	}
 
-			for _, preparedHook := range preparedHooks {
-				go func(preparedHook PreparedHook, channel chan SendCallbackReturn) {
-					var resp *http.Response
-					resp, err = http.Post(preparedHook.Callback, "application/json", bytes.NewBuffer([]byte(preparedHook.Body)))
-					if err != nil {
-						log.Println("error posting callback ", err)
-						channel <- SendCallbackReturn{
-							Callback: preparedHook.Callback,
-							Ok:       false,
-						}
-						return
-					}
-
-					defer func(Body io.ReadCloser) {
-						if err = Body.Close(); err != nil {
-							log.Println("could not close body io ", err)
-						}
-					}(resp.Body)
-
-					if resp.StatusCode == http.StatusNotFound {
-						channel <- SendCallbackReturn{
-							Callback: preparedHook.Callback,
-							Ok:       false,
-						}
-						return
-					}
-
-					if resp.StatusCode != http.StatusNoContent {
-						log.Println("strange return from discord ", resp.StatusCode)
-					}
-
-					channel <- SendCallbackReturn{
-						Callback: preparedHook.Callback,
-						Ok:       true,
-					}
-				}(preparedHook, callbackReturns)
-			}
-		}(webhook, callbackReturns)
+	// without the outbox rows, the state must not advance, the next tick tries again
+	if preparedHooks, err = repo.EnqueueOutboxWithState(preparedHooks, OutboxLease, stateUpdate); err != nil {
+		return fmt.Errorf("could not enqueue webhooks to outbox: %w", err)
+	}
+
+	if hasPendingState {
+		pendingState.commitFeedState()
+	}
+
+	if len(preparedHooks) == 0 {
+		return nil
 	}
 
-	for range topicSends {
-		callback := <-callbackReturns
+	deliverPreparedHooks(ctx, repo, preparedHooks)
+
+	return nil
+}
+
+func deliverPreparedHooks(ctx context.Context, repo Repository, preparedHooks []PreparedHook) {
+	var err error
+	callbackReturns := webhookSender.Send(ctx, preparedHooks)
+
+	for i, callback := range callbackReturns {
+		if err = markOutbox(repo, preparedHooks[i], callback); err != nil {
+			log.Println("could not update outbox ", err)
+		}
+
+		if err = repo.RecordDeliveryAttempt(preparedHooks[i], callback); err != nil {
+			log.Println("could not record delivery attempt ", err)
+		}
+
 		if callback.Ok {
-			if callback.Callback != "" {
-				repositoryMutex.Lock()
-				if err = repo.FireStampWebhook(callback.Callback); err != nil {
-					log.Println("could not stamp webhook ", callback.Callback, err)
+			if err = repo.FireStampWebhook(preparedHooks[i].WebhookId); err != nil {
+				log.Println("could not stamp webhook ", preparedHooks[i].WebhookId, err)
+			}
+
+			if callback.Message != nil {
+				if err = repo.SaveDiscordMessage(preparedHooks[i].WebhookId, preparedHooks[i].ItemKey, *callback.Message); err != nil {
+					log.Println("could not save discord message of webhook ", preparedHooks[i].WebhookId, err)
 				}
-				repositoryMutex.Unlock()
 			}
-		} else {
-			repositoryMutex.Lock()
-			if err = repo.DeleteHooksByCallback(callback.Callback); err != nil {
-				log.Println("error deleting webhook ", err)
+		} else if callback.Permanent {
+			var disabled bool
+			reason := deliveryError(callback)
+			if disabled, err = repo.RecordWebhookFailure(preparedHooks[i].WebhookId, reason, WebhookFailureThreshold, WebhookFailureWindow); err != nil {
+				log.Println("could not record webhook failure ", err)
+			} else if disabled {
+				log.Println("disabled webhook", preparedHooks[i].WebhookId, reason)
 			}
-			repositoryMutex.Unlock()
+		} else {
+			log.Println("could not deliver webhook after", callback.Attempts, "attempts", callback.StatusCode, callback.Err)
 		}
 	}
-
-	return nil
 }
 
-func Listen[CustomObj any, Feed IFeed, State any](ctx context.Context, tickRate time.Duration,
+func Listen[CustomObj any, Feed IFeed, State any](ctx context.Context, repo Repository, tickRate time.Duration,
 	feed Feed,
 	state State,
 	handleTime func(socialFeed Feed, state State, tickTime time.Time, tickRate time.Duration, repo Repository) ([]CustomObj, error),
@@ -138,7 +137,8 @@ func Listen[CustomObj any, Feed IFeed, State any](ctx context.Context, tickRate
 	for {
 		select {
 		case tickTime := <-ticker.C:
-			if err := tick(ctx, tickTime, state, feed, tickRate, handleTime, buildDiscordWebhook); err != nil {
+			// a started tick finishes its deliveries, even when the listener is stopped meanwhile
+			if err := tick(context.WithoutCancel(ctx), repo, tickTime, state, feed, tickRate, handleTime, buildDiscordWebhook); err != nil {
 				log.Println("Error in tick ", err)
 			}
 		case <-ctx.Done():
@@ -147,64 +147,3 @@ func Listen[CustomObj any, Feed IFeed, State any](ctx context.Context, tickRate
 		}
 	}
 }
-
-func SendAndCleanFailed(pack WebhookJobs, deleteByCallbackFunc func(callback string) error) error {
-	notWorked, err := send(pack)
-	if err != nil {
-		return err
-	}
-	for _, job := range notWorked {
-		if err := deleteByCallbackFunc(job); err != nil {
-			log.Println(err)
-			continue
-		}
-	}
-
-	return nil
-}
-
-func send(pack WebhookJobs) ([]string, error) {
-	marshal, err := json.Marshal(pack)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-
-	var resp *http.Response
-	resp, err = http.Post(ServerlessSenderUrl, "application/json", bytes.NewBuffer(marshal))
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Println(err)
-		}
-	}(resp.Body)
-
-	if resp.StatusCode != 200 {
-		log.Println("SocialWebhook worker returned non 200 status code:", resp.StatusCode)
-		return nil, err
-	}
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println(err)
-		return nil, err
-	}
-
-	if len(string(bodyBytes)) == 0 {
-		log.Println("SocialWebhook worker returned empty body")
-		return nil, err
-	}
-
-	var notWorkedUrls []string
-	err = json.Unmarshal(bodyBytes, &notWorkedUrls)
-	if err != nil {
-		return nil, err
-	}
-
-	return notWorkedUrls, nil
-}
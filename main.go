@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
-	"time"
+	"sync"
+	"syscall"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -22,51 +25,61 @@ func main() {
 
 	ReadEnvs()
 
-	ctx := context.Background()
+	if flag.Arg(0) == "keys" {
+		pool, err := NewPool(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer pool.Close()
 
-	var err error
-	var repo Repository
-	if err = repo.Init(ctx); err != nil {
-		log.Fatal(err)
+		if err = runKeysCommand(NewRepository(context.Background(), pool), flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	// almanax
-	var almFeeds []AlmanaxFeed
-	if almFeeds, err = repo.GetAlmanaxFeeds([]uint64{}); err != nil {
-		log.Fatal(err)
-	}
-	go func(ctx context.Context) {
-		for _, feed := range almFeeds {
-			time.Sleep(time.Second * 2)
-			go ListenAlmanax(ctx, feed)
+	webhookClient = NewWebhookClient(DeliveryRetryPolicy)
+	if SendBatchEnabled {
+		if ServerlessSenderUrl == "" {
+			log.Fatal("SERVERLESS_SENDER_URL is required for batch sending.")
 		}
-	}(ctx)
+		webhookSender = NewBatchSender(ServerlessSenderUrl)
+	} else {
+		webhookSender = NewDirectSender(webhookClient)
+	}
 
-	// twitter
-	var twitterFeeds []TwitterFeed
-	twitterFeeds, err = repo.GetTwitterFeeds([]uint64{})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := NewPool(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, feed := range twitterFeeds {
-		go ListenTwitter(ctx, feed)
-	}
+	repo := NewRepository(context.Background(), pool)
 
-	// rss
-	var rssFeeds []RssFeed
-	rssFeeds, err = repo.GetRssFeeds([]uint64{})
-	if err != nil {
+	feedSupervisor = NewFeedSupervisor(ctx, repo)
+	if err = feedSupervisor.Reconcile(ctx); err != nil {
 		log.Fatal(err)
 	}
-	for _, feed := range rssFeeds {
-		go ListenRss(ctx, feed)
-	}
-
-	repo.Deinit()
+	var background sync.WaitGroup
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		feedSupervisor.Run(FeedReloadRate)
+		feedSupervisor.Wait()
+	}()
+	go func() {
+		defer background.Done()
+		DispatchOutbox(ctx, repo)
+	}()
+	go func() {
+		defer background.Done()
+		PurgeDeletedWebhooks(ctx, repo)
+	}()
 
 	httpDataServer := &http.Server{
 		Addr:    fmt.Sprintf(":%s", ApiPort),
-		Handler: Router(),
+		Handler: Router(repo),
 	}
 
 	if *prometheusFlag {
@@ -84,9 +97,50 @@ func main() {
 		}()
 	}
 
-	log.Printf("listen on port %s\n", ApiPort)
-	if err := httpDataServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatal(err)
+	go func() {
+		log.Printf("listen on port %s\n", ApiPort)
+		if err := httpDataServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err = httpDataServer.Shutdown(shutdownCtx); err != nil {
+		log.Println("could not shut down api server ", err)
+	}
+	if httpMetricsServer != nil {
+		if err = httpMetricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Println("could not shut down metrics server ", err)
+		}
 	}
 
+	if !waitTimeout(shutdownCtx, background.Wait) {
+		log.Println("shutdown deadline exceeded, stopping with deliveries in flight")
+		return
+	}
+
+	pool.Close()
+	log.Println("shutdown complete")
+}
+
+// waitTimeout calls wait and reports whether it returned before the context was done.
+func waitTimeout(ctx context.Context, wait func()) bool {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
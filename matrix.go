@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	matrixClientPath = "/_matrix/client/v3"
+	matrixHtmlFormat = "org.matrix.custom.html"
+)
+
+// matrixHttpClient checks the rooms on creation, it is a variable so tests can trust a local homeserver.
+var matrixHttpClient = &http.Client{Timeout: 10 * time.Second}
+
+type matrixErrorResponse struct {
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+func newMatrixTarget(homeserver *string, roomId *string, accessToken *string) *MatrixTarget {
+	if homeserver == nil || roomId == nil || accessToken == nil {
+		return nil
+	}
+	return &MatrixTarget{Homeserver: *homeserver, RoomId: *roomId, AccessToken: *accessToken}
+}
+
+func matrixHomeserver(target *MatrixTarget) *string {
+	if target == nil {
+		return nil
+	}
+	homeserver := strings.TrimSuffix(target.Homeserver, "/")
+	return &homeserver
+}
+
+func matrixRoomId(target *MatrixTarget) *string {
+	if target == nil {
+		return nil
+	}
+	return &target.RoomId
+}
+
+func matrixAccessToken(target *MatrixTarget) *string {
+	if target == nil {
+		return nil
+	}
+	return &target.AccessToken
+}
+
+// matrixCallback identifies a Matrix hook by its room, room ids are unique across all homeservers.
+func matrixCallback(target MatrixTarget) string {
+	return "matrix:" + target.RoomId
+}
+
+func matrixRoomUrl(target MatrixTarget) string {
+	return strings.TrimSuffix(target.Homeserver, "/") + matrixClientPath + "/rooms/" + url.PathEscape(target.RoomId)
+}
+
+// matrixSendUrl returns the url to send a message to the room. Every message gets its own transaction id, so the
+// homeserver drops the duplicates when a delivery is retried.
+func matrixSendUrl(target MatrixTarget) string {
+	return matrixRoomUrl(target) + "/send/m.room.message/" + uuid.NewString()
+}
+
+func isMatrixSendUrl(callback string) bool {
+	return strings.Contains(callback, matrixClientPath+"/rooms/") && strings.Contains(callback, "/send/m.room.message/")
+}
+
+// isMatrixRoom checks the target and asks the homeserver whether the access token belongs to a member of the room.
+func isMatrixRoom(target *MatrixTarget) bool {
+	if target == nil || !isHttpsUrl(target.Homeserver) || target.AccessToken == "" ||
+		!strings.HasPrefix(target.RoomId, "!") || !strings.Contains(target.RoomId, ":") {
+		return false
+	}
+
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(target.Homeserver, "/")+matrixClientPath+"/joined_rooms", nil)
+	if err != nil {
+		return false
+	}
+	request.Header.Set("Authorization", "Bearer "+target.AccessToken)
+
+	response, err := matrixHttpClient.Do(request)
+	if err != nil {
+		return false
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false
+	}
+
+	var joined struct {
+		JoinedRooms []string `json:"joined_rooms"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&joined); err != nil {
+		return false
+	}
+
+	for _, roomId := range joined.JoinedRooms {
+		if roomId == target.RoomId {
+			return true
+		}
+	}
+	return false
+}
+
+// matrixDeliveryError turns the error body of the homeserver into an error. It is permanent when the access token is
+// invalid (M_UNKNOWN_TOKEN) or the user is not allowed in the room anymore (M_FORBIDDEN).
+func matrixDeliveryError(statusCode int, body []byte) (bool, error) {
+	var response matrixErrorResponse
+	if err := json.Unmarshal(body, &response); err != nil || response.ErrCode == "" {
+		return false, fmt.Errorf("matrix responded with status %d", statusCode)
+	}
+
+	permanent := response.ErrCode == "M_UNKNOWN_TOKEN" || response.ErrCode == "M_FORBIDDEN"
+	return permanent, errors.New("matrix: " + response.ErrCode + ": " + response.Error)
+}
+
+// matrixHtml renders markdown for the formatted body. Matrix clients render it as HTML, so line breaks need tags.
+func matrixHtml(markdown string) string {
+	return strings.ReplaceAll(markdownToHtml(markdown), "\n", "<br>")
+}
+
+func prepareMatrixHook(webhook IHook, body string, formattedBody string) (PreparedHook, error) {
+	target := webhook.GetMatrixTarget()
+	if target == nil {
+		return PreparedHook{}, fmt.Errorf("matrix webhook %s has no homeserver, room or access token", webhook.GetId())
+	}
+
+	jsonBody, err := json.Marshal(MatrixMessage{
+		MsgType:       "m.notice",
+		Body:          body,
+		Format:        matrixHtmlFormat,
+		FormattedBody: formattedBody,
+	})
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	return PreparedHook{
+		WebhookId:   webhook.GetId(),
+		Callback:    matrixSendUrl(*target),
+		Body:        string(jsonBody),
+		AccessToken: target.AccessToken,
+	}, nil
+}
+
+func buildMatrixHookRss(rssHookBuild RssSend, webhook IHook) (PreparedHook, error) {
+	title := rssHookBuild.Item.Title
+	if rssHookBuild.Updated {
+		title = "[" + generateUpdatedLabelRss(rssHookBuild.Feed) + "] " + title
+	}
+
+	markdown, err := shortenAndRenderDescription(rssHookBuild.Item.Description, webhook.GetPreviewLength())
+	if err != nil {
+		return PreparedHook{}, err
+	}
+	markdown = strings.TrimSpace(markdown)
+
+	body := title
+	formattedBody := "<h3>" + html.EscapeString(title) + "</h3>"
+	if rssHookBuild.Item.Link != "" {
+		body += "\n" + rssHookBuild.Item.Link
+		formattedBody = `<h3><a href="` + html.EscapeString(rssHookBuild.Item.Link) + `">` + html.EscapeString(title) + "</a></h3>"
+	}
+	if markdown != "" {
+		body += "\n\n" + markdown
+		formattedBody += "<p>" + matrixHtml(markdown) + "</p>"
+	}
+	// images in messages must be uploaded to the homeserver first, so they are linked instead
+	if optImage := findImageUrl(rssHookBuild.Item.Description); optImage != "" {
+		body += "\n\n" + optImage
+		formattedBody += `<p><a href="` + html.EscapeString(optImage) + `">🖼️ Image</a></p>`
+	}
+	formattedBody += "<p><sub>" + html.EscapeString(generateUsernameRss(rssHookBuild.Feed)) + "</sub></p>"
+
+	return prepareMatrixHook(webhook, body, formattedBody)
+}
+
+func buildMatrixHookTwitter(twitterHook TwitterSend, webhook IHook) (PreparedHook, error) {
+	author := "@" + twitterHook.Tweet.Author.Username
+	tweetText := TruncateText(twitterHook.Tweet.Text, webhook.GetPreviewLength())
+
+	body := author + "\n\n" + tweetText
+	formattedBody := "<p><b>" + html.EscapeString(author) + "</b></p><p>" + strings.ReplaceAll(html.EscapeString(tweetText), "\n", "<br>") + "</p>"
+	for _, attachment := range twitterHook.Tweet.Attachments {
+		body += "\n" + attachment
+		formattedBody += `<p><a href="` + html.EscapeString(attachment) + `">🖼️ Image</a></p>`
+	}
+
+	return prepareMatrixHook(webhook, body, formattedBody)
+}
+
+func buildMatrixHookAlmanax(almanaxSend AlmanaxSend, webhookIdx int) (PreparedHook, error) {
+	webhook := almanaxSend.Webhooks[webhookIdx]
+	intervalType := almanaxSend.IntervalType[webhookIdx]
+
+	var body, formattedBody string
+	if intervalType == "daily" {
+		localAlmData, err := getLocalAlmData(almanaxSend.BuildInfo.almData, webhook.GetTimezone())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		almLocalDate, err := almanaxDate(almanaxSend, webhook, localAlmData.GetDate())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		almBonus := localAlmData.GetBonus()
+		almBonusType := almBonus.GetType()
+		tribute := localAlmData.GetTribute()
+		almItem := tribute.GetItem()
+		kamas := formatKamas(localAlmData.GetRewardKamas())
+
+		body = fmt.Sprintf("%s\n\n⚡ %s\n%s\n\n💰 %s\n\n🙏 %dx %s", almLocalDate, almBonusType.GetName(), almBonus.GetDescription(),
+			kamas, tribute.GetQuantity(), almItem.GetName())
+		formattedBody = fmt.Sprintf("<h4>%s</h4><p>⚡ <b>%s</b><br><i>%s</i></p><p>💰 %s</p><p>🙏 %dx <b>%s</b></p>", html.EscapeString(almLocalDate),
+			html.EscapeString(almBonusType.GetName()), html.EscapeString(almBonus.GetDescription()), kamas, tribute.GetQuantity(),
+			html.EscapeString(almItem.GetName()))
+	} else {
+		localAlmData, err := buildAlmSpan(almanaxSend.TickTime, intervalType, webhook.GetTimezone(), almanaxSend.BuildInfo.almData)
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		intro := almanaxSpanIntro(almanaxSend.Feed.GetFeedName(), intervalType)
+		body = intro
+		formattedBody = "<h4>" + html.EscapeString(intro) + "</h4>"
+		var itemNames []string
+		itemsAgg := make(map[string]int32)
+		for _, almEntry := range localAlmData {
+			almLocalDate, err := almanaxDate(almanaxSend, webhook, almEntry.GetDate())
+			if err != nil {
+				return PreparedHook{}, err
+			}
+
+			almBonus := almEntry.GetBonus()
+			almBonusType := almBonus.GetType()
+			tribute := almEntry.GetTribute()
+			almItem := tribute.GetItem()
+			kamas := formatKamas(almEntry.GetRewardKamas())
+
+			body += fmt.Sprintf("\n\n%s – %s\n%s\n%s · %dx %s", almLocalDate, almBonusType.GetName(), almBonus.GetDescription(), kamas,
+				tribute.GetQuantity(), almItem.GetName())
+			formattedBody += fmt.Sprintf("<p><b>%s – %s</b><br><i>%s</i><br>%s · %dx %s</p>", html.EscapeString(almLocalDate),
+				html.EscapeString(almBonusType.GetName()), html.EscapeString(almBonus.GetDescription()), kamas, tribute.GetQuantity(),
+				html.EscapeString(almItem.GetName()))
+
+			if _, ok := itemsAgg[almItem.GetName()]; !ok {
+				itemNames = append(itemNames, almItem.GetName())
+			}
+			itemsAgg[almItem.GetName()] += tribute.GetQuantity()
+		}
+
+		totalLabel := almanaxTotalLabel(almanaxSend.Feed.GetFeedName())
+		body += "\n\n" + totalLabel
+		formattedBody += "<p><b>" + html.EscapeString(totalLabel) + "</b>"
+		for _, itemName := range itemNames {
+			body += fmt.Sprintf("\n%dx %s", itemsAgg[itemName], itemName)
+			formattedBody += fmt.Sprintf("<br>%dx %s", itemsAgg[itemName], html.EscapeString(itemName))
+		}
+		formattedBody += "</p>"
+	}
+
+	return prepareMatrixHook(webhook, body, formattedBody)
+}
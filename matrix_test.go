@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMatrixTarget(homeserver string) (*string, *string, *string) {
+	roomId := "!hooks:example.org"
+	accessToken := "syt_test"
+	return &homeserver, &roomId, &accessToken
+}
+
+func TestMatrixHtml(t *testing.T) {
+	assert.Equal(t, "<b>Patch notes</b><br>fixes &amp; <i>more</i>", matrixHtml("## Patch notes\nfixes & *more*"))
+	assert.Equal(t, "matrix:!hooks:example.org", targetCallback(WebhookTarget{Format: MatrixFormat, Matrix: &MatrixTarget{RoomId: "!hooks:example.org"}}))
+}
+
+func TestBuildHookRssMatrix(t *testing.T) {
+	homeserver, roomId, accessToken := testMatrixTarget("https://matrix.example.org")
+	webhook := RssWebhook{
+		Id:                uuid.New(),
+		Callback:          "matrix:!hooks:example.org",
+		Format:            MatrixFormat,
+		PreviewLength:     2000,
+		MatrixHomeserver:  homeserver,
+		MatrixRoomId:      roomId,
+		MatrixAccessToken: accessToken,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title:       "Maintenance",
+			Link:        "https://www.dofus.com/fr/news/1",
+			Description: `<p>Servers are <b>down</b>.</p><img src="https://static.ankama.com/image.jpg">`,
+		},
+		Webhooks: []IHook{webhook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	assert.True(t, strings.HasPrefix(hooks[0].Callback, "https://matrix.example.org/_matrix/client/v3/rooms/%21hooks:example.org/send/m.room.message/"))
+	assert.Equal(t, "syt_test", hooks[0].AccessToken)
+
+	var message MatrixMessage
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "m.notice", message.MsgType)
+	assert.Equal(t, matrixHtmlFormat, message.Format)
+	assert.Equal(t, "Maintenance\nhttps://www.dofus.com/fr/news/1\n\nServers are **down**.\n\nhttps://static.ankama.com/image.jpg", message.Body)
+	assert.Equal(t, `<h3><a href="https://www.dofus.com/fr/news/1">Maintenance</a></h3><p>Servers are <b>down</b>.</p>`+
+		`<p><a href="https://static.ankama.com/image.jpg">🖼️ Image</a></p><p><sub>Dofus3 News</sub></p>`, message.FormattedBody)
+}
+
+func TestBuildHookAlmanaxMatrix(t *testing.T) {
+	tz := "UTC"
+	homeserver, roomId, accessToken := testMatrixTarget("https://matrix.example.org")
+	webhook := AlmanaxWebhook{
+		Id:                uuid.New(),
+		Callback:          "matrix:!hooks:example.org",
+		Format:            MatrixFormat,
+		WantsIsoDate:      true,
+		DailySettings:     WebhookDailySettings{Timezone: &tz},
+		MatrixHomeserver:  homeserver,
+		MatrixRoomId:      roomId,
+		MatrixAccessToken: accessToken,
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	hooks, err := BuildHookAlmanax(testAlmanaxSend(webhook, "daily"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	var message MatrixMessage
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, fmt.Sprintf("%s\n\n⚡ Bonus <%s>\nMore loot & xp\n\n💰 12 345 K\n\n🙏 3x Wheat", today, today), message.Body)
+	assert.Equal(t, fmt.Sprintf("<h4>%s</h4><p>⚡ <b>Bonus &lt;%s&gt;</b><br><i>More loot &amp; xp</i></p><p>💰 12 345 K</p><p>🙏 3x <b>Wheat</b></p>", today, today), message.FormattedBody)
+
+	hooks, err = BuildHookAlmanax(testAlmanaxSend(webhook, "weekly"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	message = MatrixMessage{}
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.True(t, strings.HasPrefix(message.FormattedBody, "<h4>Here are the bonuses for the week!</h4>"))
+	assert.True(t, strings.HasSuffix(message.FormattedBody, "<p><b>Total</b><br>21x Wheat</p>"))
+}
+
+func TestDeliverMatrix(t *testing.T) {
+	var method, authorization, path, body string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer syt_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token passed."}`))
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/_matrix/client/v3/joined_rooms":
+			_, _ = w.Write([]byte(`{"joined_rooms":["!hooks:example.org"]}`))
+		case strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!hooks:example.org/send/m.room.message/"):
+			method = r.Method
+			authorization = r.Header.Get("Authorization")
+			path = r.URL.EscapedPath()
+			read, _ := io.ReadAll(r.Body)
+			body = string(read)
+			_, _ = w.Write([]byte(`{"event_id":"$event"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errcode":"M_FORBIDDEN","error":"You are not in this room."}`))
+		}
+	}))
+	defer server.Close()
+
+	defaultClient := matrixHttpClient
+	matrixHttpClient = server.Client()
+	defer func() { matrixHttpClient = defaultClient }()
+
+	assert.True(t, isMatrixRoom(&MatrixTarget{Homeserver: server.URL, RoomId: "!hooks:example.org", AccessToken: "syt_test"}))
+	assert.False(t, isMatrixRoom(&MatrixTarget{Homeserver: server.URL, RoomId: "!other:example.org", AccessToken: "syt_test"}))
+	assert.False(t, isMatrixRoom(&MatrixTarget{Homeserver: server.URL, RoomId: "!hooks:example.org", AccessToken: "wrong"}))
+	assert.False(t, isMatrixRoom(&MatrixTarget{Homeserver: server.URL, RoomId: "hooks", AccessToken: "syt_test"}))
+
+	homeserver, roomId, accessToken := testMatrixTarget(server.URL)
+	webhook := RssWebhook{
+		Id:                uuid.New(),
+		Format:            MatrixFormat,
+		PreviewLength:     2000,
+		MatrixHomeserver:  homeserver,
+		MatrixRoomId:      roomId,
+		MatrixAccessToken: accessToken,
+	}
+	hooks, err := BuildHookRss(RssSend{
+		Item:     gofeed.Item{Title: "Maintenance", Description: "<p>Servers are down.</p>"},
+		Webhooks: []IHook{webhook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+
+	client := NewWebhookClient(testRetryPolicy)
+	client.client = server.Client()
+	res := client.Deliver(context.Background(), hooks[0])
+	assert.True(t, res.Ok)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "Bearer syt_test", authorization)
+	assert.Equal(t, strings.TrimPrefix(hooks[0].Callback, server.URL), path)
+	assert.Equal(t, hooks[0].Body, body)
+
+	*roomId = "!other:example.org"
+	hooks, err = BuildHookRss(RssSend{
+		Item:     gofeed.Item{Title: "Maintenance"},
+		Webhooks: []IHook{webhook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+
+	res = client.Deliver(context.Background(), hooks[0])
+	assert.False(t, res.Ok)
+	assert.True(t, res.Permanent)
+	assert.EqualError(t, res.Err, "matrix: M_FORBIDDEN: You are not in this room.")
+}
+
+func TestMatrixDeliveryError(t *testing.T) {
+	permanent, err := matrixDeliveryError(http.StatusUnauthorized, []byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token passed."}`))
+	assert.True(t, permanent)
+	assert.EqualError(t, err, "matrix: M_UNKNOWN_TOKEN: Invalid access token passed.")
+
+	permanent, err = matrixDeliveryError(http.StatusForbidden, []byte(`{"errcode":"M_FORBIDDEN","error":"You are not in this room."}`))
+	assert.True(t, permanent)
+	assert.EqualError(t, err, "matrix: M_FORBIDDEN: You are not in this room.")
+
+	permanent, err = matrixDeliveryError(http.StatusBadRequest, []byte(`{"errcode":"M_TOO_LARGE","error":"Event is too large."}`))
+	assert.False(t, permanent)
+	assert.EqualError(t, err, "matrix: M_TOO_LARGE: Event is too large.")
+
+	permanent, err = matrixDeliveryError(http.StatusBadGateway, []byte("<html>Bad Gateway</html>"))
+	assert.False(t, permanent)
+	assert.EqualError(t, err, "matrix responded with status 502")
+}
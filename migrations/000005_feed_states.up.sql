@@ -0,0 +1,8 @@
+create table feed_states
+(
+    feed_id bigint not null primary key constraint fk_feed_states_feed
+        references feeds,
+    state jsonb not null,
+    updated_at timestamp with time zone default now()
+);
+alter table feed_states owner to postgres;
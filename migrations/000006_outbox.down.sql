@@ -0,0 +1,8 @@
+drop index
+    idx_outbox_status,
+    idx_outbox_webhook_id,
+    idx_outbox_created_at;
+
+drop table outbox;
+
+drop type outbox_status;
@@ -0,0 +1,25 @@
+create type outbox_status as enum ('pending', 'delivered', 'failed');
+
+create table outbox
+(
+    id bigserial not null primary key,
+    webhook_id uuid not null
+        constraint fk_outbox_webhook
+            references webhooks,
+    feed_id bigint
+        constraint fk_outbox_feed
+            references feeds,
+    callback text not null,
+    body text not null,
+    status outbox_status not null default 'pending',
+    attempts int not null default 0,
+    last_error text,
+    locked_until timestamp with time zone,
+    created_at timestamp with time zone default now(),
+    updated_at timestamp with time zone default now(),
+    delivered_at timestamp with time zone
+);
+alter table outbox owner to postgres;
+create index idx_outbox_status on outbox (status);
+create index idx_outbox_webhook_id on outbox (webhook_id);
+create index idx_outbox_created_at on outbox (created_at);
@@ -0,0 +1,5 @@
+drop index
+    idx_delivery_attempts_webhook_id_created_at,
+    idx_delivery_attempts_created_at;
+
+drop table delivery_attempts;
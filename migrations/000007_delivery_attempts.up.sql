@@ -0,0 +1,19 @@
+create table delivery_attempts
+(
+    id bigserial not null primary key,
+    webhook_id uuid not null
+        constraint fk_delivery_attempts_webhook
+            references webhooks,
+    feed_id bigint
+        constraint fk_delivery_attempts_feed
+            references feeds,
+    ok boolean not null,
+    status_code int,
+    attempts int not null default 1,
+    latency_ms int not null,
+    error text,
+    created_at timestamp with time zone default now()
+);
+alter table delivery_attempts owner to postgres;
+create index idx_delivery_attempts_webhook_id_created_at on delivery_attempts (webhook_id, created_at desc);
+create index idx_delivery_attempts_created_at on delivery_attempts (created_at);
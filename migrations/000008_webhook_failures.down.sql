@@ -0,0 +1,3 @@
+alter table webhooks drop column failure_count;
+alter table webhooks drop column first_failed_at;
+alter table webhooks drop column disabled_reason;
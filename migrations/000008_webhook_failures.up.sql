@@ -0,0 +1,3 @@
+alter table webhooks add column failure_count int not null default 0;
+alter table webhooks add column first_failed_at timestamp with time zone;
+alter table webhooks add column disabled_reason text;
@@ -0,0 +1,3 @@
+drop index idx_api_keys_key_hash;
+
+drop table api_keys;
@@ -0,0 +1,13 @@
+create table api_keys
+(
+    id uuid default gen_random_uuid() not null
+        primary key,
+    name text not null,
+    key_hash text not null,
+    scopes text[] not null,
+    created_at timestamp with time zone default now(),
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+alter table api_keys owner to postgres;
+create unique index idx_api_keys_key_hash on api_keys (key_hash);
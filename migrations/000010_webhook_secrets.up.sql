@@ -0,0 +1 @@
+alter table webhooks add column secret text;
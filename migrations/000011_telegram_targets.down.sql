@@ -0,0 +1,2 @@
+alter table webhooks drop column telegram_chat_id;
+alter table webhooks drop column telegram_bot_token;
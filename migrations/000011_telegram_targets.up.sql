@@ -0,0 +1,2 @@
+alter table webhooks add column telegram_bot_token text;
+alter table webhooks add column telegram_chat_id text;
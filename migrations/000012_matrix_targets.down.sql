@@ -0,0 +1,3 @@
+alter table webhooks drop column matrix_access_token;
+alter table webhooks drop column matrix_room_id;
+alter table webhooks drop column matrix_homeserver;
@@ -0,0 +1,3 @@
+alter table webhooks add column matrix_homeserver text;
+alter table webhooks add column matrix_room_id text;
+alter table webhooks add column matrix_access_token text;
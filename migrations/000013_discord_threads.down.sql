@@ -0,0 +1 @@
+alter table webhooks drop column discord_thread;
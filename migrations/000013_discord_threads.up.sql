@@ -0,0 +1 @@
+alter table webhooks add column discord_thread jsonb;
@@ -0,0 +1,3 @@
+alter table outbox drop column item_key;
+
+drop table discord_messages;
@@ -0,0 +1,15 @@
+create table discord_messages
+(
+    webhook_id uuid not null
+        constraint fk_discord_messages_webhook
+            references webhooks,
+    item_key text not null,
+    message_id text not null,
+    channel_id text not null,
+    created_at timestamp with time zone default now(),
+    updated_at timestamp with time zone default now(),
+    primary key (webhook_id, item_key)
+);
+alter table discord_messages owner to postgres;
+
+alter table outbox add column item_key text;
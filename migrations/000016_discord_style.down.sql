@@ -0,0 +1,3 @@
+alter table webhooks drop column discord_embed_color;
+alter table webhooks drop column discord_avatar_url;
+alter table webhooks drop column discord_username;
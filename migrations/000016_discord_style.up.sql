@@ -0,0 +1,3 @@
+alter table webhooks add column discord_username text;
+alter table webhooks add column discord_avatar_url text;
+alter table webhooks add column discord_embed_color integer;
@@ -0,0 +1 @@
+update outbox set callback = regexp_replace(callback, '/bot([0-9]+):[^/]+/', '/bot\1/') where callback like 'https://api.telegram.org/bot%';
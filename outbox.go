@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const outboxBatchSize = 100
+
+// DispatchOutbox delivers pending outbox entries. Ticks deliver their own entries right away, so this only picks up
+// entries whose delivery was interrupted (e.g. a crash) or failed temporarily.
+func DispatchOutbox(ctx context.Context, repo Repository) {
+	ticker := time.NewTicker(OutboxPollingRate)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := drainOutbox(ctx, repo); err != nil {
+				log.Println("Error while draining outbox ", err)
+			}
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func drainOutbox(ctx context.Context, repo Repository) error {
+	var err error
+	// claimed deliveries are finished, even when the dispatcher is stopped meanwhile
+	deliveryCtx := context.WithoutCancel(ctx)
+	repo = repo.WithContext(deliveryCtx)
+
+	if err = repo.PruneOutbox(time.Now().Add(-OutboxRetention)); err != nil {
+		return err
+	}
+
+	if err = repo.PruneDeliveryAttempts(time.Now().Add(-DeliveryHistoryRetention)); err != nil {
+		return err
+	}
+
+	if err = repo.PruneDiscordMessages(); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		var preparedHooks []PreparedHook
+		if preparedHooks, err = repo.ClaimOutbox(outboxBatchSize, OutboxLease); err != nil {
+			return err
+		}
+
+		if len(preparedHooks) == 0 {
+			return nil
+		}
+
+		deliverPreparedHooks(deliveryCtx, repo, preparedHooks)
+	}
+
+	return nil
+}
+
+func markOutbox(repo Repository, preparedHook PreparedHook, callback SendCallbackReturn) error {
+	if preparedHook.OutboxId == 0 {
+		return nil
+	}
+
+	if callback.Ok {
+		return repo.MarkOutboxDelivered(preparedHook.OutboxId)
+	}
+
+	return repo.MarkOutboxFailed(preparedHook.OutboxId, deliveryError(callback), callback.Permanent, OutboxMaxAttempts, OutboxPollingRate)
+}
+
+func deliveryError(callback SendCallbackReturn) string {
+	if callback.Err != nil {
+		return callback.Err.Error()
+	}
+	return fmt.Sprintf("target responded with status %d", callback.StatusCode)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OutboxTestSuite struct {
+	suite.Suite
+	db Repository
+}
+
+func (suite *OutboxTestSuite) SetupSuite() {
+	ReadEnvs()
+
+	pool, err := NewPool(context.Background())
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	suite.db = NewRepository(context.Background(), pool)
+}
+
+func (suite *OutboxTestSuite) TearDownSuite() {
+	suite.db.conn.Close()
+}
+
+func (suite *OutboxTestSuite) TearDownTest() {
+	if err := testutilCleartables(); err != nil {
+		suite.T().Fatal(err)
+	}
+}
+
+func (suite *OutboxTestSuite) createHook(callback string) uuid.UUID {
+	id, err := suite.db.CreateSocialHook(RSSWebhookType, SocialHookCreate{
+		Callback:      callback,
+		Subscriptions: []string{"dofus3-fr-official-news"},
+		Format:        "discord",
+	})
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	return id
+}
+
+func (suite *OutboxTestSuite) Test_EnqueueAndClaim() {
+	id := suite.createHook("https://discord.com/api/webhooks/123/abc")
+
+	hooks, err := suite.db.EnqueueOutbox([]PreparedHook{
+		{
+			WebhookId: id,
+			FeedId:    1,
+			Callback:  "https://discord.com/api/webhooks/123/abc",
+			Body:      "{}",
+		},
+	}, time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), int64(0), hooks[0].OutboxId)
+
+	// still leased by the tick
+	claimed, err := suite.db.ClaimOutbox(10, time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), claimed, 0)
+
+	_, err = suite.db.EnqueueOutbox([]PreparedHook{
+		{
+			WebhookId: id,
+			Callback:  "https://discord.com/api/webhooks/123/abc",
+			Body:      `{"content": "crashed"}`,
+		},
+	}, -time.Second)
+	assert.Nil(suite.T(), err)
+
+	claimed, err = suite.db.ClaimOutbox(10, time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), claimed, 1)
+	assert.Equal(suite.T(), `{"content": "crashed"}`, claimed[0].Body)
+	assert.Equal(suite.T(), id, claimed[0].WebhookId)
+
+	claimed, err = suite.db.ClaimOutbox(10, time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), claimed, 0)
+}
+
+func (suite *OutboxTestSuite) Test_MarkFailed() {
+	id := suite.createHook("https://discord.com/api/webhooks/123/abc")
+
+	hooks, err := suite.db.EnqueueOutbox([]PreparedHook{
+		{
+			WebhookId: id,
+			Callback:  "https://discord.com/api/webhooks/123/abc",
+			Body:      "{}",
+		},
+	}, -time.Second)
+	assert.Nil(suite.T(), err)
+
+	assert.Nil(suite.T(), suite.db.MarkOutboxFailed(hooks[0].OutboxId, "status code 502", false, 2, -time.Second))
+	status, attempts, err := testutilGetOutboxStatus(hooks[0].OutboxId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "pending", status)
+	assert.Equal(suite.T(), 1, attempts)
+
+	assert.Nil(suite.T(), suite.db.MarkOutboxFailed(hooks[0].OutboxId, "status code 502", false, 2, -time.Second))
+	status, attempts, err = testutilGetOutboxStatus(hooks[0].OutboxId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "failed", status)
+	assert.Equal(suite.T(), 2, attempts)
+
+	claimed, err := suite.db.ClaimOutbox(10, time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), claimed, 0)
+}
+
+func (suite *OutboxTestSuite) Test_Deliver() {
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer discord.Close()
+
+	id := suite.createHook(discord.URL)
+	hooks, err := suite.db.EnqueueOutbox([]PreparedHook{
+		{
+			WebhookId: id,
+			Callback:  discord.URL,
+			Body:      "{}",
+		},
+	}, time.Minute)
+	assert.Nil(suite.T(), err)
+
+	deliverPreparedHooks(context.Background(), suite.db, hooks)
+
+	status, attempts, err := testutilGetOutboxStatus(hooks[0].OutboxId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "delivered", status)
+	assert.Equal(suite.T(), 1, attempts)
+
+	hook, err := suite.db.GetSocialHook(RSSWebhookType, id)
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), hook.GetLastFiredAt())
+}
+
+func (suite *OutboxTestSuite) Test_DeliverSavesDiscordMessage() {
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "1200", "channel_id": "1100", "content": "posted"}`))
+	}))
+	defer discord.Close()
+
+	id := suite.createHook(discord.URL)
+	_, err := suite.db.EnqueueOutbox([]PreparedHook{
+		{
+			WebhookId: id,
+			Callback:  discord.URL + "?wait=true",
+			Body:      "{}",
+			ItemKey:   "guid:1",
+		},
+	}, -time.Second)
+	assert.Nil(suite.T(), err)
+
+	// the item key survives a crash between enqueueing and delivering
+	claimed, err := suite.db.ClaimOutbox(10, time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), claimed, 1)
+	assert.Equal(suite.T(), "guid:1", claimed[0].ItemKey)
+
+	deliverPreparedHooks(context.Background(), suite.db, claimed)
+
+	messages, err := suite.db.GetDiscordMessages("guid:1", []uuid.UUID{id})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), map[uuid.UUID]DiscordMessageRef{id: {MessageId: "1200", ChannelId: "1100"}}, messages)
+
+	assert.Nil(suite.T(), suite.db.SaveDiscordMessage(id, "guid:1", DiscordMessageRef{MessageId: "1300", ChannelId: "1100"}))
+	messages, err = suite.db.GetDiscordMessages("guid:1", []uuid.UUID{id})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1300", messages[id].MessageId)
+
+	messages, err = suite.db.GetDiscordMessages("guid:2", []uuid.UUID{id})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), messages, 0)
+}
+
+func (suite *OutboxTestSuite) Test_PruneDiscordMessages() {
+	id := suite.createHook("https://discord.com/api/webhooks/1/a")
+	assert.Nil(suite.T(), suite.db.SaveDiscordMessage(id, "guid:1", DiscordMessageRef{MessageId: "1200", ChannelId: "1100"}))
+	assert.Nil(suite.T(), suite.db.SaveDiscordMessage(id, "guid:2", DiscordMessageRef{MessageId: "1201", ChannelId: "1100"}))
+	assert.Nil(suite.T(), suite.db.SetRssState(1, RssState{SeenItems: []RssSeenItem{{Key: "guid:1", Hash: 1}}}))
+
+	assert.Nil(suite.T(), suite.db.PruneDiscordMessages())
+
+	messages, err := suite.db.GetDiscordMessages("guid:1", []uuid.UUID{id})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), messages, 1)
+
+	messages, err = suite.db.GetDiscordMessages("guid:2", []uuid.UUID{id})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), messages, 0)
+}
+
+func (suite *OutboxTestSuite) Test_FailureThreshold() {
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer discord.Close()
+
+	id := suite.createHook(discord.URL)
+
+	// a single unknown webhook response does not remove the hook anymore
+	deliverPreparedHooks(context.Background(), suite.db, []PreparedHook{{WebhookId: id, Callback: discord.URL, Body: "{}"}})
+	found, err := suite.db.HasSocialWebhook(RSSWebhookType, id)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), found)
+
+	hook, err := suite.db.GetSocialHook(RSSWebhookType, id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, hook.GetFailureCount())
+
+	// failures must span the window
+	disabled, err := suite.db.RecordWebhookFailure(id, "target responded with status 404", 2, time.Hour)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), disabled)
+
+	// a success resets the streak
+	assert.Nil(suite.T(), suite.db.FireStampWebhook(id))
+	hook, err = suite.db.GetSocialHook(RSSWebhookType, id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, hook.GetFailureCount())
+
+	disabled, err = suite.db.RecordWebhookFailure(id, "target responded with status 404", 2, -time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), disabled)
+	disabled, err = suite.db.RecordWebhookFailure(id, "target responded with status 404", 2, -time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), disabled)
+
+	found, err = suite.db.HasSocialWebhook(RSSWebhookType, id)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), found)
+
+	hookOut, err := getSocial(RSSWebhookType, id, suite.db)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, hookOut.FailureCount)
+	assert.Equal(suite.T(), "target responded with status 404", *hookOut.DisabledReason)
+
+	_, err = getSocial(TwitterWebhookType, id, suite.db)
+	assert.EqualError(suite.T(), err, "not found")
+}
+
+type fakeSender struct {
+	sent []PreparedHook
+}
+
+func (s *fakeSender) Send(_ context.Context, hooks []PreparedHook) []SendCallbackReturn {
+	s.sent = append(s.sent, hooks...)
+	callbackReturns := make([]SendCallbackReturn, len(hooks))
+	for i, hook := range hooks {
+		callbackReturns[i] = SendCallbackReturn{Callback: hook.Callback, Ok: true, Attempts: 1}
+	}
+	return callbackReturns
+}
+
+func (suite *OutboxTestSuite) Test_DeliverWithSender() {
+	sender := &fakeSender{}
+	defaultSender := webhookSender
+	webhookSender = sender
+	defer func() {
+		webhookSender = defaultSender
+	}()
+
+	id := suite.createHook("https://discord.com/api/webhooks/123/abc")
+	hooks, err := suite.db.EnqueueOutbox([]PreparedHook{
+		{
+			WebhookId: id,
+			Callback:  "https://discord.com/api/webhooks/123/abc",
+			Body:      "{}",
+		},
+	}, time.Minute)
+	assert.Nil(suite.T(), err)
+
+	deliverPreparedHooks(context.Background(), suite.db, hooks)
+
+	assert.Len(suite.T(), sender.sent, 1)
+	status, _, err := testutilGetOutboxStatus(hooks[0].OutboxId)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "delivered", status)
+}
+
+func TestOutboxTestSuite(t *testing.T) {
+	suite.Run(t, new(OutboxTestSuite))
+}
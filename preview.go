@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// maxPreviewLength is the longest description a preview renders, the length of the default.
+const maxPreviewLength = 2000
+
+// latestRssItem is the most recently published item of a feed, or the first one if the feed has no dates.
+func latestRssItem(items []*gofeed.Item) *gofeed.Item {
+	var latest *gofeed.Item
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if latest == nil || (item.PublishedParsed != nil && (latest.PublishedParsed == nil || item.PublishedParsed.After(*latest.PublishedParsed))) {
+			latest = item
+		}
+	}
+	return latest
+}
+
+// previewRssHook renders the Discord message a new RSS webhook would get for the item.
+func previewRssHook(createHook SocialHookCreate, feed RssFeed, item gofeed.Item) (string, error) {
+	webhook := RssWebhook{
+		Format:            DiscordFormat,
+		PreviewLength:     *createHook.PreviewLength,
+		DiscordThread:     normalizeDiscordThread(createHook.DiscordThread),
+		Template:          nullableText(createHook.Template),
+		DiscordUsername:   nullableText(createHook.Username),
+		DiscordAvatarUrl:  nullableText(createHook.AvatarUrl),
+		DiscordEmbedColor: nullableColor(createHook.EmbedColor),
+	}
+
+	hooks, err := BuildDiscordHookRss(RssSend{
+		Item:     item,
+		Webhooks: []IHook{webhook},
+		Feed:     feed,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hooks[0].Body, nil
+}
+
+// previewAlmanaxHook renders the Discord message a new almanax webhook would get for the interval.
+func previewAlmanaxHook(createHook AlmanaxHookPost, feed AlmanaxFeed, intervalType string, buildInfo AlmanaxHookBuildInfo, tickTime time.Time) (string, error) {
+	webhook := AlmanaxWebhook{
+		Format:            DiscordFormat,
+		DailySettings:     *createHook.DailySettings,
+		WantsIsoDate:      createHook.WantsIsoDate != nil && *createHook.WantsIsoDate,
+		Mentions:          createHook.Mentions,
+		DiscordThread:     normalizeDiscordThread(createHook.DiscordThread),
+		Template:          nullableText(createHook.Template),
+		DiscordUsername:   nullableText(createHook.Username),
+		DiscordAvatarUrl:  nullableText(createHook.AvatarUrl),
+		DiscordEmbedColor: nullableColor(createHook.EmbedColor),
+	}
+
+	hooks, err := buildDiscordHookAlmanax(AlmanaxSend{
+		Feed:            feed,
+		BuildInfo:       buildInfo,
+		Webhooks:        []IHook{webhook},
+		OnlyPreMentions: []bool{false},
+		IntervalType:    []string{intervalType},
+		TickTime:        tickTime,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(hooks) == 0 {
+		return "", errors.New("nothing to preview")
+	}
+
+	return hooks[0].Body, nil
+}
+
+// validatePreviewStyle checks the Discord settings of a preview request. It writes the error response itself if it
+// returns false.
+func validatePreviewStyle(w http.ResponseWriter, webhookType string, thread *DiscordThread, template *string, style DiscordStyle) bool {
+	if msg := validateDiscordThread(webhookType, DiscordFormat, thread); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return false
+	}
+
+	if msg := validateDiscordTemplate(webhookType, DiscordFormat, template); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return false
+	}
+
+	if msg := validateDiscordStyle(DiscordFormat, style); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func writePreview(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(body))
+}
+
+func handlePreviewRss(w http.ResponseWriter, r *http.Request) {
+	metricsIncSocialCRUD(RSSWebhookType)
+
+	var err error
+	var previewHook SocialHookCreate
+	if err = json.NewDecoder(r.Body).Decode(&previewHook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if previewHook.Format != "" && previewHook.Format != DiscordFormat {
+		http.Error(w, "Previews are only available for Discord webhooks.", http.StatusBadRequest)
+		return
+	}
+
+	if len(previewHook.Subscriptions) == 0 {
+		http.Error(w, "Subscriptions are required.", http.StatusBadRequest)
+		return
+	}
+
+	if !validatePreviewStyle(w, RSSWebhookType, previewHook.DiscordThread, previewHook.Template, DiscordStyle{Username: previewHook.Username, AvatarUrl: previewHook.AvatarUrl, EmbedColor: previewHook.EmbedColor}) {
+		return
+	}
+
+	if previewHook.PreviewLength == nil {
+		defaultRssPreviewLength := 2000
+		previewHook.PreviewLength = &defaultRssPreviewLength
+	}
+
+	if *previewHook.PreviewLength < 0 || *previewHook.PreviewLength > maxPreviewLength {
+		http.Error(w, fmt.Sprintf("Preview length must be between 0 and %d.", maxPreviewLength), http.StatusBadRequest)
+		return
+	}
+
+	repo := requestRepository(r)
+
+	// the preview shows the first subscription, the others get the same message for their items
+	var found bool
+	var feedIds []uint64
+	if found, feedIds, err = repo.HasGetSocialFeeds(RSSWebhookType, previewHook.Subscriptions[:1]); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Some feeds not found.", http.StatusBadRequest)
+		return
+	}
+
+	var feeds []RssFeed
+	if feeds, err = repo.GetRssFeeds(feedIds); err != nil || len(feeds) == 0 {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	var rssFeed *gofeed.Feed
+	if rssFeed, err = fetchRssFeed(r.Context(), feeds[0].GetRSSUrl()); err != nil {
+		http.Error(w, "Could not read the RSS feed.", http.StatusBadGateway)
+		return
+	}
+
+	item := latestRssItem(rssFeed.Items)
+	if item == nil {
+		http.Error(w, "The feed has no items yet.", http.StatusNotFound)
+		return
+	}
+
+	var body string
+	if body, err = previewRssHook(previewHook, feeds[0], *item); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	writePreview(w, body)
+}
+
+func handlePreviewAlmanax(w http.ResponseWriter, r *http.Request) {
+	requestsCRUDTotal.Inc()
+	requestsCRUDAlmanax.Inc()
+
+	var err error
+	var previewHook AlmanaxHookPost
+	if err = json.NewDecoder(r.Body).Decode(&previewHook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if previewHook.Format != "" && previewHook.Format != DiscordFormat {
+		http.Error(w, "Previews are only available for Discord webhooks.", http.StatusBadRequest)
+		return
+	}
+
+	if len(previewHook.Subscriptions) == 0 {
+		http.Error(w, "Subscriptions are required.", http.StatusBadRequest)
+		return
+	}
+
+	intervalType := "daily"
+	if interval := r.URL.Query().Get("interval"); interval != "" {
+		if _, ok := validateIntervals([]string{interval}); !ok {
+			http.Error(w, "An interval must be one of daily, weekly or monthly.", http.StatusBadRequest)
+			return
+		}
+		intervalType = strings.ToLower(interval)
+	}
+
+	defaultTz := "Europe/Paris"
+	defaultTzOffset := 0
+	if previewHook.DailySettings == nil {
+		previewHook.DailySettings = &WebhookDailySettings{}
+	}
+
+	if previewHook.DailySettings.Timezone == nil {
+		previewHook.DailySettings.Timezone = &defaultTz
+	}
+
+	if previewHook.DailySettings.MidnightOffset == nil {
+		previewHook.DailySettings.MidnightOffset = &defaultTzOffset
+	}
+
+	if _, err = time.LoadLocation(*previewHook.DailySettings.Timezone); err != nil {
+		http.Error(w, "Timezone not valid.", http.StatusBadRequest)
+		return
+	}
+
+	if previewHook.Mentions != nil && len(*previewHook.Mentions) > 150 {
+		http.Error(w, "Too many mentions.", http.StatusBadRequest)
+		return
+	}
+
+	if !validatePreviewStyle(w, AlmanaxWebhookType, previewHook.DiscordThread, previewHook.Template, DiscordStyle{Username: previewHook.Username, AvatarUrl: previewHook.AvatarUrl, EmbedColor: previewHook.EmbedColor}) {
+		return
+	}
+
+	repo := requestRepository(r)
+
+	// the preview shows the first subscription, the others only differ in language
+	var found bool
+	var feedIds []uint64
+	if found, feedIds, err = repo.HasGetAlmanaxFeeds(previewHook.Subscriptions[:1]); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Some feeds not found.", http.StatusBadRequest)
+		return
+	}
+
+	var feeds []AlmanaxFeed
+	if feeds, err = repo.GetAlmanaxFeeds(feedIds); err != nil || len(feeds) == 0 {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	tickTime := time.Now()
+	buildInfo := AlmanaxHookBuildInfo{}
+	if buildInfo.almData, err = fetchAlmanaxData(r.Context(), feeds[0].Language, tickTime); err != nil {
+		http.Error(w, "Could not reach Almanax API.", http.StatusBadGateway)
+		return
+	}
+
+	if buildInfo.translations, err = repo.GetAllWeekdayTranslations(); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	var body string
+	if body, err = previewAlmanaxHook(previewHook, feeds[0], intervalType, buildInfo, tickTime); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	writePreview(w, body)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestRssItem(t *testing.T) {
+	older := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	assert.Nil(t, latestRssItem(nil))
+	assert.Equal(t, "first", latestRssItem([]*gofeed.Item{{Title: "first"}, {Title: "second"}}).Title)
+	assert.Equal(t, "newer", latestRssItem([]*gofeed.Item{{Title: "older", PublishedParsed: &older}, {Title: "newer", PublishedParsed: &newer}}).Title)
+	assert.Equal(t, "dated", latestRssItem([]*gofeed.Item{{Title: "undated"}, {Title: "dated", PublishedParsed: &older}}).Title)
+}
+
+func TestPreviewRssHook(t *testing.T) {
+	previewLength := 20
+	color := 0xFF0000
+	body, err := previewRssHook(SocialHookCreate{
+		PreviewLength: &previewLength,
+		DiscordThread: &DiscordThread{Forum: true},
+		EmbedColor:    &color,
+	}, RssFeed{ApiReadableId: "dofus3-fr-official-news"}, gofeed.Item{
+		Title:       "La fusion des serveurs",
+		Link:        "https://www.dofus.com/fr/news/1",
+		Description: "<p>Les serveurs fusionnent bientôt, voici comment.</p>",
+	})
+	assert.Nil(t, err)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(body), &message))
+	assert.Equal(t, "Dofus3 News", message.Username)
+	assert.Equal(t, "La fusion des serveurs", message.ThreadName)
+	assert.Equal(t, "La fusion des serveurs", *message.Embeds[0].Title)
+	assert.Equal(t, "Les serveurs ...", *message.Embeds[0].Description)
+	assert.Equal(t, color, message.Embeds[0].Color)
+}
+
+func TestPreviewAlmanaxHook(t *testing.T) {
+	tz := "UTC"
+	isoDate := true
+	username := "Daily Almanax"
+	buildInfo := testAlmanaxSend(AlmanaxWebhook{}, "daily").BuildInfo
+	today := time.Now().UTC().Format("2006-01-02")
+
+	body, err := previewAlmanaxHook(AlmanaxHookPost{
+		DailySettings: &WebhookDailySettings{Timezone: &tz},
+		WantsIsoDate:  &isoDate,
+		Username:      &username,
+	}, AlmanaxFeed{HumanReadableId: "almanax_en", Language: "en"}, "daily", buildInfo, time.Now())
+	assert.Nil(t, err)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(body), &message))
+	assert.Equal(t, username, message.Username)
+	assert.Equal(t, today, *message.Embeds[0].Title)
+	assert.Equal(t, ":zap: Bonus <"+today+">", message.Embeds[0].Fields[0].Name)
+
+	body, err = previewAlmanaxHook(AlmanaxHookPost{
+		DailySettings: &WebhookDailySettings{Timezone: &tz},
+		WantsIsoDate:  &isoDate,
+	}, AlmanaxFeed{HumanReadableId: "almanax_en", Language: "en"}, "weekly", buildInfo, time.Now())
+	assert.Nil(t, err)
+
+	message = DiscordWebhook{}
+	assert.Nil(t, json.Unmarshal([]byte(body), &message))
+	assert.Equal(t, "Here are the bonuses for the week!", *message.Content)
+	assert.Len(t, message.Embeds[0].Fields, 8)
+}
+
+func TestPreviewRejectsInvalidHooks(t *testing.T) {
+	tests := []struct {
+		handler http.HandlerFunc
+		url     string
+		body    string
+		message string
+	}{
+		{handlePreviewRss, "/webhooks/rss/preview", `{"format": "slack", "subscriptions": ["dofus3-fr-official-news"]}`, "Previews are only available for Discord webhooks."},
+		{handlePreviewRss, "/webhooks/rss/preview", `{"format": "discord"}`, "Subscriptions are required."},
+		{handlePreviewRss, "/webhooks/rss/preview", `{"subscriptions": ["dofus3-fr-official-news"], "embed_color": 16777216}`, "Embed color must be between 0 and 16777215 (0xFFFFFF), or negative for the built-in one."},
+		{handlePreviewRss, "/webhooks/rss/preview", `{"subscriptions": ["dofus3-fr-official-news"], "preview_length": -1}`, "Preview length must be between 0 and 2000."},
+		{handlePreviewRss, "/webhooks/rss/preview", `{"subscriptions": ["dofus3-fr-official-news"], "preview_length": 2001}`, "Preview length must be between 0 and 2000."},
+		{handlePreviewAlmanax, "/webhooks/almanax/preview?interval=yearly", `{"subscriptions": ["almanax_en"]}`, "An interval must be one of daily, weekly or monthly."},
+		{handlePreviewAlmanax, "/webhooks/almanax/preview", `{"subscriptions": ["almanax_en"], "daily_settings": {"timezone": "Mars/Olympus"}}`, "Timezone not valid."},
+		{handlePreviewAlmanax, "/webhooks/almanax/preview", `{"subscriptions": ["almanax_en"], "discord_thread": {"forum": true}}`, "Forum posts are only supported for RSS webhooks."},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		test.handler(recorder, httptest.NewRequest(http.MethodPost, test.url, strings.NewReader(test.body)))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, test.body)
+		assert.Equal(t, test.message, strings.TrimSpace(recorder.Body.String()))
+	}
+}
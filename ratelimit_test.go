@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIpRateLimiterBucket(t *testing.T) {
+	now := time.Now()
+	limiter := NewIpRateLimiter(1, 2, nil)
+	limiter.now = func() time.Time { return now }
+
+	ok, _ := limiter.allow("1.2.3.4")
+	assert.True(t, ok)
+	ok, _ = limiter.allow("1.2.3.4")
+	assert.True(t, ok)
+	ok, wait := limiter.allow("1.2.3.4")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	ok, _ = limiter.allow("5.6.7.8")
+	assert.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, wait = limiter.allow("1.2.3.4")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow("1.2.3.4")
+	assert.True(t, ok)
+
+	now = now.Add(time.Hour)
+	limiter.allow("1.2.3.4")
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestKeyedRateLimiterKeys(t *testing.T) {
+	limiter := newKeyedRateLimiter(1.0/60, 1)
+
+	ok, _ := limiter.allow("5b0f6c1e-8f4e-4d2b-9a43-3c1f0a9d7e21")
+	assert.True(t, ok)
+	ok, wait := limiter.allow("5b0f6c1e-8f4e-4d2b-9a43-3c1f0a9d7e21")
+	assert.False(t, ok)
+	assert.InDelta(t, time.Minute.Seconds(), wait.Seconds(), 1)
+
+	ok, _ = limiter.allow("0d1f2e3c-4b5a-6978-8a9b-acbdcedf0123")
+	assert.True(t, ok)
+}
+
+func TestIpRateLimiterClientIp(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	assert.Nil(t, err)
+	limiter := NewIpRateLimiter(1, 1, trusted)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "1.2.3.4:5000"
+	r.Header.Set("X-Forwarded-For", "9.9.9.9")
+	assert.Equal(t, "1.2.3.4", limiter.clientIp(r))
+
+	r.RemoteAddr = "192.168.1.1:5000"
+	r.Header.Set("X-Forwarded-For", "9.9.9.9, 1.2.3.4, 10.0.0.2")
+	assert.Equal(t, "1.2.3.4", limiter.clientIp(r))
+
+	r.Header.Set("X-Forwarded-For", "10.0.0.3, 10.0.0.2")
+	assert.Equal(t, "10.0.0.3", limiter.clientIp(r))
+
+	r.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "192.168.1.1", limiter.clientIp(r))
+
+	_, err = parseTrustedProxies("not-an-ip")
+	assert.NotNil(t, err)
+}
+
+func TestIpRateLimiterMiddleware(t *testing.T) {
+	limiter := NewIpRateLimiter(0.5, 1, nil)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+}
@@ -2,35 +2,44 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/lib/pq"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var repositoryMutex = sync.Mutex{}
-
 type Repository struct {
 	conn *pgxpool.Pool
 	ctx  context.Context
 }
 
-func (r *Repository) Init(ctx context.Context) error {
-	var err error
-	r.ctx = ctx
-	r.conn, err = pgxpool.New(ctx, PostgresUrl)
-	return err
+// NewPool connects to the database. The pool is meant to be created once and shared by every repository.
+func NewPool(ctx context.Context) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(PostgresUrl)
+	if err != nil {
+		return nil, err
+	}
+	config.MaxConns = PostgresPoolSize
+	return pgxpool.NewWithConfig(ctx, config)
 }
 
-func (r *Repository) Deinit() {
-	r.conn.Close()
-	r.conn = nil
+func NewRepository(ctx context.Context, pool *pgxpool.Pool) Repository {
+	return Repository{
+		conn: pool,
+		ctx:  ctx,
+	}
+}
+
+// WithContext returns a repository on the same pool that runs its queries with the given context.
+func (r *Repository) WithContext(ctx context.Context) Repository {
+	return NewRepository(ctx, r.conn)
 }
 
 func (r *Repository) GetAllWeekdayTranslations() (map[string]map[string]string, error) {
@@ -73,12 +82,34 @@ func (r *Repository) GetTranslationFor(language string, enWeekday string) (strin
 	return translation, err
 }
 
-func (r *Repository) FireStampWebhook(callback string) error {
+// FireStampWebhook marks a successful delivery, which also ends a streak of failures.
+func (r *Repository) FireStampWebhook(id uuid.UUID) error {
 	var err error
-	_, err = r.conn.Exec(r.ctx, "update webhooks set last_fired_at = $1 where callback = $2", time.Now(), callback)
+	_, err = r.conn.Exec(r.ctx, "update webhooks set last_fired_at = $1, failure_count = 0, first_failed_at = null where id = $2", time.Now(), id)
 	return err
 }
 
+// RecordWebhookFailure counts a definitive delivery failure. The webhook is soft-deleted with the given reason once
+// it failed at least threshold times in a row and the first of those failures is older than window, so a short
+// outage on Discord's side can't remove it. Reports whether the webhook got disabled.
+func (r *Repository) RecordWebhookFailure(id uuid.UUID, reason string, threshold int, window time.Duration) (bool, error) {
+	var err error
+	var disabled bool
+	err = r.conn.QueryRow(r.ctx, "update webhooks set failure_count = failure_count + 1, first_failed_at = coalesce(first_failed_at, now()), deleted_at = case when failure_count + 1 >= $2 and coalesce(first_failed_at, now()) <= $3 then now() else deleted_at end, disabled_reason = case when failure_count + 1 >= $2 and coalesce(first_failed_at, now()) <= $3 then $4 else disabled_reason end where id = $1 and deleted_at is null returning deleted_at is not null",
+		id, threshold, time.Now().Add(-window), reason).Scan(&disabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return disabled, err
+}
+
+func (r *Repository) IsDisabledWebhook(webhookType string, id uuid.UUID) (bool, error) {
+	var err error
+	var exists bool
+	err = r.conn.QueryRow(r.ctx, "select exists(select 1 from webhooks where id = $1 and type = $2 and deleted_at is not null and disabled_reason is not null)", id, webhookType).Scan(&exists)
+	return exists, err
+}
+
 func (r *Repository) GetAlmanaxFeeds(ids []uint64) ([]AlmanaxFeed, error) {
 	var err error
 	var feeds []AlmanaxFeed
@@ -169,6 +200,38 @@ func GetRssFeeds(ids []uint64, repo Repository) ([]RssFeed, error) {
 	return repo.GetRssFeeds(ids)
 }
 
+func (r *Repository) GetRssState(feedId uint64) (RssState, bool, error) {
+	var err error
+	var state RssState
+	var rawState []byte
+	err = r.conn.QueryRow(r.ctx, "select state from feed_states where feed_id = $1", feedId).Scan(&rawState)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return state, false, nil
+		}
+		return state, false, err
+	}
+
+	if err = json.Unmarshal(rawState, &state); err != nil {
+		return RssState{}, false, err
+	}
+
+	return state, true, nil
+}
+
+const upsertFeedStateQuery = "insert into feed_states (feed_id, state, updated_at) values ($1, $2, $3) on conflict (feed_id) do update set state = excluded.state, updated_at = excluded.updated_at"
+
+func (r *Repository) SetRssState(feedId uint64, state RssState) error {
+	var err error
+	var rawState []byte
+	if rawState, err = json.Marshal(state); err != nil {
+		return err
+	}
+
+	_, err = r.conn.Exec(r.ctx, upsertFeedStateQuery, feedId, rawState, time.Now())
+	return err
+}
+
 func (r *Repository) HasAlmanaxWebhook(id uuid.UUID) (bool, error) {
 	var err error
 	var exists bool
@@ -379,7 +442,7 @@ func (r *Repository) FindSocialFeedId(socialType string, humanId string) (uint64
 func (r *Repository) CreateSocialHook(socialType string, createHook SocialHookCreate) (uuid.UUID, error) {
 	var err error
 	var id uuid.UUID
-	err = r.conn.QueryRow(r.ctx, "insert into webhooks (format, callback, type) values ($1, $2, $3) returning id", createHook.Format, createHook.Callback, socialType).Scan(&id)
+	err = r.conn.QueryRow(r.ctx, "insert into webhooks (format, callback, type, secret, telegram_bot_token, telegram_chat_id, matrix_homeserver, matrix_room_id, matrix_access_token, discord_thread, template, discord_username, discord_avatar_url, discord_embed_color) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) returning id", createHook.Format, createHook.Callback, socialType, createHook.Secret, telegramBotToken(createHook.Telegram), telegramChatId(createHook.Telegram), matrixHomeserver(createHook.Matrix), matrixRoomId(createHook.Matrix), matrixAccessToken(createHook.Matrix), normalizeDiscordThread(createHook.DiscordThread), nullableText(createHook.Template), nullableText(createHook.Username), nullableText(createHook.AvatarUrl), nullableColor(createHook.EmbedColor)).Scan(&id)
 	if err != nil {
 		return uuid.Nil, err
 	}
@@ -552,11 +615,63 @@ func (r *Repository) UpdateAlmanaxHook(hook AlmanaxHookPut, id uuid.UUID) error
 		}
 	}
 
+	if hook.DiscordThread != nil {
+		if err = r.setDiscordThread(id, hook.DiscordThread); err != nil {
+			return err
+		}
+	}
+
+	if hook.Template != nil {
+		if err = r.setTemplate(id, hook.Template); err != nil {
+			return err
+		}
+	}
+
+	if err = r.setDiscordStyle(id, DiscordStyle{Username: hook.Username, AvatarUrl: hook.AvatarUrl, EmbedColor: hook.EmbedColor}); err != nil {
+		return err
+	}
+
 	err = r.setUpdatedHookTimestamp(id)
 
 	return err
 }
 
+// setDiscordThread replaces the thread settings of a webhook, empty settings remove them.
+func (r *Repository) setDiscordThread(id uuid.UUID, thread *DiscordThread) error {
+	_, err := r.conn.Exec(r.ctx, "update webhooks set discord_thread = $1 where id = $2", normalizeDiscordThread(thread), id)
+	return err
+}
+
+// setTemplate replaces the template of a webhook, an empty template removes it.
+func (r *Repository) setTemplate(id uuid.UUID, template *string) error {
+	_, err := r.conn.Exec(r.ctx, "update webhooks set template = $1 where id = $2", nullableText(template), id)
+	return err
+}
+
+// setDiscordStyle changes the style fields that are set, empty texts reset them to the built-in ones.
+func (r *Repository) setDiscordStyle(id uuid.UUID, style DiscordStyle) error {
+	var err error
+	if style.Username != nil {
+		if _, err = r.conn.Exec(r.ctx, "update webhooks set discord_username = $1 where id = $2", nullableText(style.Username), id); err != nil {
+			return err
+		}
+	}
+
+	if style.AvatarUrl != nil {
+		if _, err = r.conn.Exec(r.ctx, "update webhooks set discord_avatar_url = $1 where id = $2", nullableText(style.AvatarUrl), id); err != nil {
+			return err
+		}
+	}
+
+	if style.EmbedColor != nil {
+		if _, err = r.conn.Exec(r.ctx, "update webhooks set discord_embed_color = $1 where id = $2", nullableColor(style.EmbedColor), id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *Repository) setUpdatedHookTimestamp(id uuid.UUID) error {
 	_, err := r.conn.Exec(r.ctx, "update webhooks set updated_at = $1 where id = $2", time.Now(), id)
 	return err
@@ -635,6 +750,22 @@ func (r *Repository) UpdateSocialHook(socialType string, hook ISocialHookUpdate)
 		}
 	}
 
+	if hook.GetDiscordThread() != nil {
+		if err = r.setDiscordThread(hook.GetId(), hook.GetDiscordThread()); err != nil {
+			return err
+		}
+	}
+
+	if hook.GetTemplate() != nil {
+		if err = r.setTemplate(hook.GetId(), hook.GetTemplate()); err != nil {
+			return err
+		}
+	}
+
+	if err = r.setDiscordStyle(hook.GetId(), hook.GetDiscordStyle()); err != nil {
+		return err
+	}
+
 	return r.setUpdatedHookTimestamp(hook.GetId())
 }
 
@@ -643,13 +774,13 @@ func (r *Repository) GetSocialHook(socialType string, id uuid.UUID) (ISocialHook
 	switch socialType {
 	case TwitterWebhookType:
 		var webhook TwitterWebhook
-		err = r.conn.QueryRow(r.ctx, "select w.id, w.last_fired_at, w.callback, w.created_at, w.updated_at, tw.preview_length, w.format, tw.whitelist, tw.blacklist from twitter_webhooks tw inner join webhooks w on w.id = tw.id where tw.id = $1 and w.deleted_at is null", id).
-			Scan(&webhook.Id, &webhook.LastFiredAt, &webhook.Callback, &webhook.CreatedAt, &webhook.UpdatedAt, &webhook.PreviewLength, &webhook.Format, &webhook.Whitelist, &webhook.Blacklist)
+		err = r.conn.QueryRow(r.ctx, "select w.id, w.last_fired_at, w.callback, w.created_at, w.updated_at, tw.preview_length, w.format, tw.whitelist, tw.blacklist, w.failure_count, w.disabled_reason, w.secret, w.telegram_bot_token, w.telegram_chat_id, w.matrix_homeserver, w.matrix_room_id, w.matrix_access_token, w.discord_thread, w.template, w.discord_username, w.discord_avatar_url, w.discord_embed_color from twitter_webhooks tw inner join webhooks w on w.id = tw.id where tw.id = $1 and (w.deleted_at is null or w.disabled_reason is not null)", id).
+			Scan(&webhook.Id, &webhook.LastFiredAt, &webhook.Callback, &webhook.CreatedAt, &webhook.UpdatedAt, &webhook.PreviewLength, &webhook.Format, &webhook.Whitelist, &webhook.Blacklist, &webhook.FailureCount, &webhook.DisabledReason, &webhook.Secret, &webhook.TelegramBotToken, &webhook.TelegramChatId, &webhook.MatrixHomeserver, &webhook.MatrixRoomId, &webhook.MatrixAccessToken, &webhook.DiscordThread, &webhook.Template, &webhook.DiscordUsername, &webhook.DiscordAvatarUrl, &webhook.DiscordEmbedColor)
 		return webhook, err
 	case RSSWebhookType:
 		var webhook RssWebhook
-		err = r.conn.QueryRow(r.ctx, "select w.id, w.last_fired_at, w.callback, w.created_at, w.updated_at, rw.preview_length, w.format, rw.whitelist, rw.blacklist from rss_webhooks rw inner join webhooks w on w.id = rw.id where rw.id = $1 and w.deleted_at is null", id).
-			Scan(&webhook.Id, &webhook.LastFiredAt, &webhook.Callback, &webhook.CreatedAt, &webhook.UpdatedAt, &webhook.PreviewLength, &webhook.Format, &webhook.Whitelist, &webhook.Blacklist)
+		err = r.conn.QueryRow(r.ctx, "select w.id, w.last_fired_at, w.callback, w.created_at, w.updated_at, rw.preview_length, w.format, rw.whitelist, rw.blacklist, w.failure_count, w.disabled_reason, w.secret, w.telegram_bot_token, w.telegram_chat_id, w.matrix_homeserver, w.matrix_room_id, w.matrix_access_token, w.discord_thread, w.template, w.discord_username, w.discord_avatar_url, w.discord_embed_color from rss_webhooks rw inner join webhooks w on w.id = rw.id where rw.id = $1 and (w.deleted_at is null or w.disabled_reason is not null)", id).
+			Scan(&webhook.Id, &webhook.LastFiredAt, &webhook.Callback, &webhook.CreatedAt, &webhook.UpdatedAt, &webhook.PreviewLength, &webhook.Format, &webhook.Whitelist, &webhook.Blacklist, &webhook.FailureCount, &webhook.DisabledReason, &webhook.Secret, &webhook.TelegramBotToken, &webhook.TelegramChatId, &webhook.MatrixHomeserver, &webhook.MatrixRoomId, &webhook.MatrixAccessToken, &webhook.DiscordThread, &webhook.Template, &webhook.DiscordUsername, &webhook.DiscordAvatarUrl, &webhook.DiscordEmbedColor)
 		return webhook, err
 	default:
 		return nil, errors.New("unknown social type")
@@ -691,7 +822,7 @@ func (r *Repository) CreateAlmanaxHook(createHook CreateAlmanaxHook) (uuid.UUID,
 	var err error
 	var id uuid.UUID
 
-	err = r.conn.QueryRow(r.ctx, "insert into webhooks (format, callback, type) values ($1, $2, $3) returning id", createHook.Format, createHook.Callback, "almanax").Scan(&id)
+	err = r.conn.QueryRow(r.ctx, "insert into webhooks (format, callback, type, secret, telegram_bot_token, telegram_chat_id, matrix_homeserver, matrix_room_id, matrix_access_token, discord_thread, template, discord_username, discord_avatar_url, discord_embed_color) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) returning id", createHook.Format, createHook.Callback, "almanax", createHook.Secret, telegramBotToken(createHook.Telegram), telegramChatId(createHook.Telegram), matrixHomeserver(createHook.Matrix), matrixRoomId(createHook.Matrix), matrixAccessToken(createHook.Matrix), normalizeDiscordThread(createHook.DiscordThread), nullableText(createHook.Template), nullableText(createHook.Username), nullableText(createHook.AvatarUrl), nullableColor(createHook.EmbedColor)).Scan(&id)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
@@ -743,9 +874,9 @@ func (r *Repository) GetAlmanaxHook(id uuid.UUID) (AlmanaxWebhook, error) {
 	var err error
 
 	var webhook AlmanaxWebhook
-	if err = r.conn.QueryRow(r.ctx, "select w.id, w.last_fired_at, w.callback, w.created_at, w.updated_at, w.format, aw.daily_timezone, aw.daily_midnight_offset, aw.wants_iso_date, aw.whitelist, aw.blacklist, aw.intervals, aw.weekly_weekday from almanax_webhooks aw inner join webhooks w on w.id = aw.id where w.id = $1 and w.deleted_at is null", id).
+	if err = r.conn.QueryRow(r.ctx, "select w.id, w.last_fired_at, w.callback, w.created_at, w.updated_at, w.format, aw.daily_timezone, aw.daily_midnight_offset, aw.wants_iso_date, aw.whitelist, aw.blacklist, aw.intervals, aw.weekly_weekday, w.failure_count, w.disabled_reason, w.secret, w.telegram_bot_token, w.telegram_chat_id, w.matrix_homeserver, w.matrix_room_id, w.matrix_access_token, w.discord_thread, w.template, w.discord_username, w.discord_avatar_url, w.discord_embed_color from almanax_webhooks aw inner join webhooks w on w.id = aw.id where w.id = $1 and (w.deleted_at is null or w.disabled_reason is not null)", id).
 		Scan(&webhook.Id, &webhook.LastFiredAt, &webhook.Callback, &webhook.CreatedAt, &webhook.UpdatedAt, &webhook.Format,
-			&webhook.DailySettings.Timezone, &webhook.DailySettings.MidnightOffset, &webhook.WantsIsoDate, &webhook.BonusWhitelist, &webhook.BonusBlacklist, &webhook.Intervals, &webhook.WeeklyWeekday); err != nil {
+			&webhook.DailySettings.Timezone, &webhook.DailySettings.MidnightOffset, &webhook.WantsIsoDate, &webhook.BonusWhitelist, &webhook.BonusBlacklist, &webhook.Intervals, &webhook.WeeklyWeekday, &webhook.FailureCount, &webhook.DisabledReason, &webhook.Secret, &webhook.TelegramBotToken, &webhook.TelegramChatId, &webhook.MatrixHomeserver, &webhook.MatrixRoomId, &webhook.MatrixAccessToken, &webhook.DiscordThread, &webhook.Template, &webhook.DiscordUsername, &webhook.DiscordAvatarUrl, &webhook.DiscordEmbedColor); err != nil {
 		return AlmanaxWebhook{}, err
 	}
 
@@ -789,19 +920,7 @@ func (r *Repository) GetTwitterSubsForFeed(feed IFeed) ([]HasIdBlackWhiteList[st
 			return nil, err
 		}
 
-		webhookFull := TwitterWebhook{
-			Id:            webhookId,
-			Callback:      webhook.GetCallback(),
-			CreatedAt:     webhook.GetCreatedAt(),
-			UpdatedAt:     webhook.GetUpdatedAt(),
-			Format:        webhook.GetFormat(),
-			Blacklist:     webhook.GetBlacklist(),
-			Whitelist:     webhook.GetWhitelist(),
-			PreviewLength: webhook.GetPreviewLength(),
-			LastFiredAt:   webhook.GetLastFiredAt(),
-		}
-
-		webhooks = append(webhooks, webhookFull)
+		webhooks = append(webhooks, webhook.(TwitterWebhook))
 	}
 
 	return webhooks, nil
@@ -833,19 +952,7 @@ func (r *Repository) GetRSSSubsForFeed(feed IFeed) ([]HasIdBlackWhiteList[string
 			return nil, err
 		}
 
-		webhookFull := RssWebhook{
-			Id:            webhookId,
-			Callback:      webhook.GetCallback(),
-			CreatedAt:     webhook.GetCreatedAt(),
-			UpdatedAt:     webhook.GetUpdatedAt(),
-			Format:        webhook.GetFormat(),
-			Blacklist:     webhook.GetBlacklist(),
-			Whitelist:     webhook.GetWhitelist(),
-			PreviewLength: webhook.GetPreviewLength(),
-			LastFiredAt:   webhook.GetLastFiredAt(),
-		}
-
-		webhooks = append(webhooks, webhookFull)
+		webhooks = append(webhooks, webhook.(RssWebhook))
 	}
 
 	return webhooks, nil
@@ -882,3 +989,460 @@ func (r *Repository) GetAlmanaxSubsForFeed(feed IFeed) ([]AlmanaxWebhook, error)
 
 	return webhooks, nil
 }
+
+func nullableFeedId(feedId uint64) *uint64 {
+	if feedId == 0 {
+		return nil
+	}
+	return &feedId
+}
+
+// nullableText stores empty texts as null, so clearing a setting with "" resets it.
+func nullableText(text *string) *string {
+	if isEmptyText(text) {
+		return nil
+	}
+	return text
+}
+
+// isDefaultColor reports whether the embed color is unset or negative, which stands for the built-in color.
+func isDefaultColor(color *int) bool {
+	return color == nil || *color < 0
+}
+
+// nullableColor stores the built-in embed color as null.
+func nullableColor(color *int) *int {
+	if isDefaultColor(color) {
+		return nil
+	}
+	return color
+}
+
+// EnqueueOutbox stores the prepared hooks as pending deliveries. They are leased to the caller, so the dispatcher
+// only picks them up when the caller did not finish them in time.
+func (r *Repository) EnqueueOutbox(hooks []PreparedHook, lease time.Duration) ([]PreparedHook, error) {
+	return r.EnqueueOutboxWithState(hooks, lease, nil)
+}
+
+// EnqueueOutboxWithState stores the prepared hooks and the new state of the feed in one transaction. Either all hooks
+// are enqueued and the feed moves on, or nothing is stored and the tick is repeated.
+func (r *Repository) EnqueueOutboxWithState(hooks []PreparedHook, lease time.Duration, stateUpdate *FeedStateUpdate) ([]PreparedHook, error) {
+	tx, err := r.conn.Begin(r.ctx)
+	if err != nil {
+		return hooks, err
+	}
+	defer func() {
+		_ = tx.Rollback(r.ctx)
+	}()
+
+	lockedUntil := time.Now().Add(lease)
+	for i := range hooks {
+		err = tx.QueryRow(r.ctx, "insert into outbox (webhook_id, feed_id, callback, body, item_key, locked_until) values ($1, $2, $3, $4, nullif($5, ''), $6) returning id",
+			hooks[i].WebhookId, nullableFeedId(hooks[i].FeedId), hooks[i].Callback, hooks[i].Body, hooks[i].ItemKey, lockedUntil).Scan(&hooks[i].OutboxId)
+		if err != nil {
+			return hooks, err
+		}
+	}
+
+	if stateUpdate != nil {
+		var rawState []byte
+		if rawState, err = json.Marshal(stateUpdate.State); err != nil {
+			return hooks, err
+		}
+		if _, err = tx.Exec(r.ctx, upsertFeedStateQuery, stateUpdate.FeedId, rawState, time.Now()); err != nil {
+			return hooks, err
+		}
+	}
+
+	return hooks, tx.Commit(r.ctx)
+}
+
+// GetDiscordMessages returns the messages posted for an RSS item, by webhook.
+func (r *Repository) GetDiscordMessages(itemKey string, webhookIds []uuid.UUID) (map[uuid.UUID]DiscordMessageRef, error) {
+	messages := make(map[uuid.UUID]DiscordMessageRef)
+	rows, err := r.conn.Query(r.ctx, "select webhook_id, message_id, channel_id from discord_messages where item_key = $1 and webhook_id = any($2)", itemKey, webhookIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var webhookId uuid.UUID
+		var message DiscordMessageRef
+		if err = rows.Scan(&webhookId, &message.MessageId, &message.ChannelId); err != nil {
+			return nil, err
+		}
+		messages[webhookId] = message
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *Repository) SaveDiscordMessage(webhookId uuid.UUID, itemKey string, message DiscordMessageRef) error {
+	_, err := r.conn.Exec(r.ctx, "insert into discord_messages (webhook_id, item_key, message_id, channel_id) values ($1, $2, $3, $4) on conflict (webhook_id, item_key) do update set message_id = excluded.message_id, channel_id = excluded.channel_id, updated_at = now()",
+		webhookId, itemKey, message.MessageId, message.ChannelId)
+	return err
+}
+
+// ClaimOutbox leases up to limit pending deliveries that are not leased by someone else.
+func (r *Repository) ClaimOutbox(limit int, lease time.Duration) ([]PreparedHook, error) {
+	var err error
+	var hooks []PreparedHook
+	var rows pgx.Rows
+	rows, err = r.conn.Query(r.ctx, "update outbox set locked_until = $1, updated_at = now() from webhooks w where w.id = outbox.webhook_id and outbox.id in (select o.id from outbox o inner join webhooks w on w.id = o.webhook_id where o.status = 'pending' and w.deleted_at is null and (o.locked_until is null or o.locked_until < now()) order by o.id limit $2 for update of o skip locked) returning outbox.id, outbox.webhook_id, outbox.feed_id, outbox.callback, outbox.body, coalesce(w.secret, ''), coalesce(w.matrix_access_token, w.telegram_bot_token, ''), coalesce(outbox.item_key, '')",
+		time.Now().Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hook PreparedHook
+		var feedId *uint64
+		if err = rows.Scan(&hook.OutboxId, &hook.WebhookId, &feedId, &hook.Callback, &hook.Body, &hook.Secret, &hook.AccessToken, &hook.ItemKey); err != nil {
+			return nil, err
+		}
+		if feedId != nil {
+			hook.FeedId = *feedId
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+func (r *Repository) MarkOutboxDelivered(id int64) error {
+	_, err := r.conn.Exec(r.ctx, "update outbox set status = 'delivered', attempts = attempts + 1, last_error = null, locked_until = null, delivered_at = now(), updated_at = now() where id = $1", id)
+	return err
+}
+
+// MarkOutboxFailed records a failed delivery. It stays pending for another try after retryDelay, unless the failure
+// is permanent or the delivery ran out of attempts.
+func (r *Repository) MarkOutboxFailed(id int64, lastError string, permanent bool, maxAttempts int, retryDelay time.Duration) error {
+	_, err := r.conn.Exec(r.ctx, "update outbox set status = case when $2 or attempts + 1 >= $3 then 'failed'::outbox_status else 'pending'::outbox_status end, attempts = attempts + 1, last_error = $4, locked_until = $5, updated_at = now() where id = $1",
+		id, permanent, maxAttempts, lastError, time.Now().Add(retryDelay))
+	return err
+}
+
+func (r *Repository) PruneOutbox(before time.Time) error {
+	_, err := r.conn.Exec(r.ctx, "delete from outbox where status <> 'pending' and created_at < $1", before)
+	return err
+}
+
+func (r *Repository) RecordDeliveryAttempt(hook PreparedHook, callback SendCallbackReturn) error {
+	var statusCode *int
+	if callback.StatusCode != 0 {
+		statusCode = &callback.StatusCode
+	}
+
+	var lastError *string
+	if callback.Err != nil {
+		errText := callback.Err.Error()
+		lastError = &errText
+	}
+
+	_, err := r.conn.Exec(r.ctx, "insert into delivery_attempts (webhook_id, feed_id, ok, status_code, attempts, latency_ms, error) values ($1, $2, $3, $4, $5, $6, $7)",
+		hook.WebhookId, nullableFeedId(hook.FeedId), callback.Ok, statusCode, callback.Attempts, callback.Latency.Milliseconds(), lastError)
+	return err
+}
+
+// GetDeliveryAttempts returns a page of the delivery log of a webhook, newest first, and the total count.
+func (r *Repository) GetDeliveryAttempts(webhookId uuid.UUID, limit int, offset int) ([]DeliveryAttemptDTO, int, error) {
+	var err error
+	var total int
+	if err = r.conn.QueryRow(r.ctx, "select count(*) from delivery_attempts where webhook_id = $1", webhookId).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	var rows pgx.Rows
+	rows, err = r.conn.Query(r.ctx, "select d.id, coalesce(rf.api_readable_id, tf.human_readable_id, af.human_readable_id), d.ok, d.status_code, d.attempts, d.latency_ms, d.error, d.created_at from delivery_attempts d left join rss_feeds rf on rf.id = d.feed_id left join twitter_feeds tf on tf.id = d.feed_id left join almanax_feeds af on af.id = d.feed_id where d.webhook_id = $1 order by d.created_at desc, d.id desc limit $2 offset $3",
+		webhookId, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []DeliveryAttemptDTO{}
+	for rows.Next() {
+		var delivery DeliveryAttemptDTO
+		if err = rows.Scan(&delivery.Id, &delivery.Feed, &delivery.Ok, &delivery.StatusCode, &delivery.Attempts, &delivery.LatencyMs, &delivery.Error, &delivery.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, total, rows.Err()
+}
+
+// PruneDiscordMessages forgets the messages of RSS items that dropped out of the seen items of every feed. Those items
+// are not compared anymore, so their messages would never be edited again.
+func (r *Repository) PruneDiscordMessages() error {
+	_, err := r.conn.Exec(r.ctx, "delete from discord_messages dm where not exists (select 1 from feed_states fs, jsonb_array_elements(fs.state -> 'seen_items') seen where seen ->> 'key' = dm.item_key)")
+	return err
+}
+
+func (r *Repository) PruneDeliveryAttempts(before time.Time) error {
+	_, err := r.conn.Exec(r.ctx, "delete from delivery_attempts where created_at < $1", before)
+	return err
+}
+
+// GetDeletedHookTarget returns the target of a soft-deleted webhook of the given type.
+func (r *Repository) GetDeletedHookTarget(webhookType string, id uuid.UUID) (WebhookTarget, bool, error) {
+	var err error
+	var target WebhookTarget
+	var botToken, chatId *string
+	var homeserver, roomId, accessToken *string
+	err = r.conn.QueryRow(r.ctx, "select format, callback, telegram_bot_token, telegram_chat_id, matrix_homeserver, matrix_room_id, matrix_access_token from webhooks where id = $1 and type = $2 and deleted_at is not null", id, webhookType).
+		Scan(&target.Format, &target.Callback, &botToken, &chatId, &homeserver, &roomId, &accessToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return WebhookTarget{}, false, nil
+		}
+		return WebhookTarget{}, false, err
+	}
+	target.Telegram = newTelegramTarget(botToken, chatId)
+	target.Matrix = newMatrixTarget(homeserver, roomId, accessToken)
+	return target, true, nil
+}
+
+func (r *Repository) RestoreHook(id uuid.UUID) error {
+	_, err := r.conn.Exec(r.ctx, "update webhooks set deleted_at = null, disabled_reason = null, failure_count = 0, first_failed_at = null, updated_at = $1 where id = $2", time.Now(), id)
+	return err
+}
+
+// PurgeDeletedHooks removes webhooks that were soft-deleted before the given time, together with everything
+// referencing them.
+func (r *Repository) PurgeDeletedHooks(before time.Time) (int64, error) {
+	tx, err := r.conn.Begin(r.ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(r.ctx)
+	}()
+
+	var ids []uuid.UUID
+	if err = tx.QueryRow(r.ctx, "select coalesce(array_agg(id), '{}') from webhooks where deleted_at < $1", before).Scan(&ids); err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var mentionIds []uuid.UUID
+	if err = tx.QueryRow(r.ctx, "select coalesce(array_agg(discord_mention_id), '{}') from almanax_mentions where almanax_webhook_id = any($1)", ids).Scan(&mentionIds); err != nil {
+		return 0, err
+	}
+
+	statements := []string{
+		"delete from outbox where webhook_id = any($1)",
+		"delete from delivery_attempts where webhook_id = any($1)",
+		"delete from discord_messages where webhook_id = any($1)",
+		"delete from subscriptions where webhook_id = any($1)",
+		"delete from almanax_mentions where almanax_webhook_id = any($1)",
+		"delete from almanax_webhooks where id = any($1)",
+		"delete from rss_webhooks where id = any($1)",
+		"delete from twitter_webhooks where id = any($1)",
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(r.ctx, statement, ids); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err = tx.Exec(r.ctx, "delete from discord_mentions where id = any($1)", mentionIds); err != nil {
+		return 0, err
+	}
+
+	var tag pgconn.CommandTag
+	if tag, err = tx.Exec(r.ctx, "delete from webhooks where id = any($1)", ids); err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), tx.Commit(r.ctx)
+}
+
+const adminFeedSelect = "select f.id, case when rf.id is not null then 'rss' when tf.id is not null then 'twitter' else 'almanax' end, coalesce(rf.api_readable_id, tf.human_readable_id, af.human_readable_id), rf.url, tf.twitter_id, af.language, coalesce(rf.is_official, tf.is_official, false), f.deleted_at is not null, f.created_at from feeds f left join rss_feeds rf on rf.id = f.id left join twitter_feeds tf on tf.id = f.id left join almanax_feeds af on af.id = f.id where (rf.id is not null or tf.id is not null or af.id is not null)"
+
+func scanAdminFeed(row pgx.Row) (AdminFeedDTO, error) {
+	var feed AdminFeedDTO
+	err := row.Scan(&feed.Id, &feed.Type, &feed.Name, &feed.Url, &feed.TwitterId, &feed.Language, &feed.IsOfficial, &feed.Disabled, &feed.CreatedAt)
+	return feed, err
+}
+
+// GetAdminFeeds lists all feeds of all types, including disabled ones.
+func (r *Repository) GetAdminFeeds() ([]AdminFeedDTO, error) {
+	var err error
+	var rows pgx.Rows
+	if rows, err = r.conn.Query(r.ctx, adminFeedSelect+" order by f.id"); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := []AdminFeedDTO{}
+	for rows.Next() {
+		var feed AdminFeedDTO
+		if feed, err = scanAdminFeed(rows); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+
+	return feeds, rows.Err()
+}
+
+func (r *Repository) GetAdminFeed(id uint64) (AdminFeedDTO, error) {
+	feed, err := scanAdminFeed(r.conn.QueryRow(r.ctx, adminFeedSelect+" and f.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return AdminFeedDTO{}, errors.New("not found")
+	}
+	return feed, err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (r *Repository) CreateFeed(feedType string, feed AdminFeedPost) (uint64, error) {
+	tx, err := r.conn.Begin(r.ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(r.ctx)
+	}()
+
+	var id uint64
+	if err = tx.QueryRow(r.ctx, "insert into feeds (created_at) values (now()) returning id").Scan(&id); err != nil {
+		return 0, err
+	}
+
+	switch feedType {
+	case RSSWebhookType:
+		_, err = tx.Exec(r.ctx, "insert into rss_feeds (id, url, api_readable_id, is_official) values ($1, $2, $3, $4)", id, feed.Url, feed.Name, feed.IsOfficial)
+	case TwitterWebhookType:
+		_, err = tx.Exec(r.ctx, "insert into twitter_feeds (id, twitter_id, human_readable_id, is_official) values ($1, $2, $3, $4)", id, feed.TwitterId, feed.Name, feed.IsOfficial)
+	case AlmanaxWebhookType:
+		_, err = tx.Exec(r.ctx, "insert into almanax_feeds (id, human_readable_id, language) values ($1, $2, $3)", id, feed.Name, feed.Language)
+	default:
+		return 0, errors.New("unknown feed type")
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, errors.New("name already exists")
+		}
+		return 0, err
+	}
+
+	return id, tx.Commit(r.ctx)
+}
+
+func (r *Repository) RenameFeed(feedType string, id uint64, name string) error {
+	var err error
+	switch feedType {
+	case RSSWebhookType:
+		_, err = r.conn.Exec(r.ctx, "update rss_feeds set api_readable_id = $1 where id = $2", name, id)
+	case TwitterWebhookType:
+		_, err = r.conn.Exec(r.ctx, "update twitter_feeds set human_readable_id = $1 where id = $2", name, id)
+	case AlmanaxWebhookType:
+		_, err = r.conn.Exec(r.ctx, "update almanax_feeds set human_readable_id = $1 where id = $2", name, id)
+	default:
+		return errors.New("unknown feed type")
+	}
+	if isUniqueViolation(err) {
+		return errors.New("name already exists")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn.Exec(r.ctx, "update feeds set updated_at = now() where id = $1", id)
+	return err
+}
+
+// SetFeedDisabled soft-deletes a feed or brings it back. Disabled feeds have no listener and can't be subscribed to.
+func (r *Repository) SetFeedDisabled(id uint64, disabled bool) error {
+	var err error
+	if disabled {
+		_, err = r.conn.Exec(r.ctx, "update feeds set deleted_at = now(), updated_at = now() where id = $1 and deleted_at is null", id)
+	} else {
+		_, err = r.conn.Exec(r.ctx, "update feeds set deleted_at = null, updated_at = now() where id = $1", id)
+	}
+	return err
+}
+
+// DeleteFeed removes a feed for good, including the subscriptions of webhooks to it. The delivery history is kept.
+func (r *Repository) DeleteFeed(id uint64) error {
+	tx, err := r.conn.Begin(r.ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(r.ctx)
+	}()
+
+	statements := []string{
+		"update outbox set feed_id = null where feed_id = $1",
+		"update delivery_attempts set feed_id = null where feed_id = $1",
+		"delete from feed_states where feed_id = $1",
+		"delete from subscriptions where feed_id = $1",
+		"delete from rss_feeds where id = $1",
+		"delete from twitter_feeds where id = $1",
+		"delete from almanax_feeds where id = $1",
+		"delete from feeds where id = $1",
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(r.ctx, statement, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(r.ctx)
+}
+
+func (r *Repository) CreateApiKey(name string, keyHash string, scopes []string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.conn.QueryRow(r.ctx, "insert into api_keys (name, key_hash, scopes) values ($1, $2, $3) returning id", name, keyHash, scopes).Scan(&id)
+	return id, err
+}
+
+// UseApiKey looks up an active key by its hash and stamps its usage.
+func (r *Repository) UseApiKey(keyHash string) (ApiKey, error) {
+	var key ApiKey
+	err := r.conn.QueryRow(r.ctx, "update api_keys set last_used_at = now() where key_hash = $1 and revoked_at is null returning id, name, scopes, created_at, last_used_at, revoked_at", keyHash).
+		Scan(&key.Id, &key.Name, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ApiKey{}, errors.New("not found")
+	}
+	return key, err
+}
+
+func (r *Repository) GetApiKeys() ([]ApiKey, error) {
+	var err error
+	var rows pgx.Rows
+	if rows, err = r.conn.Query(r.ctx, "select id, name, scopes, created_at, last_used_at, revoked_at from api_keys order by created_at"); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []ApiKey
+	for rows.Next() {
+		var key ApiKey
+		if err = rows.Scan(&key.Id, &key.Name, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *Repository) RevokeApiKey(id uuid.UUID) (bool, error) {
+	tag, err := r.conn.Exec(r.ctx, "update api_keys set revoked_at = now() where id = $1 and revoked_at is null", id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
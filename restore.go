@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const webhookPurgeRate = time.Hour
+
+func handleRestoreHook(webhookType string, w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(w, "Invalid id.", http.StatusBadRequest)
+		return
+	}
+
+	repo := requestRepository(r)
+
+	var target WebhookTarget
+	var found bool
+	if target, found, err = repo.GetDeletedHookTarget(webhookType, parsedId); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Not found.", http.StatusNotFound)
+		return
+	}
+
+	if !isValidTarget(target) {
+		http.Error(w, "Callback is not valid anymore.", http.StatusBadRequest)
+		return
+	}
+
+	var hasCallback bool
+	if hasCallback, err = repo.hasWebhookCallback(target.Callback, webhookType+"_webhooks"); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	if hasCallback {
+		http.Error(w, "Callback already exists.", http.StatusConflict)
+		return
+	}
+
+	if err = repo.RestoreHook(parsedId); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	var hookOut any
+	if webhookType == AlmanaxWebhookType {
+		var alm AlmanaxWebhook
+		alm, err = getAlm(parsedId, repo)
+		hookOut = toDTO(alm)
+	} else {
+		hookOut, err = getSocial(webhookType, parsedId, repo)
+	}
+	if err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(hookOut); err != nil {
+		http.Error(w, "Error encoding the response.", http.StatusInternalServerError)
+		return
+	}
+}
+
+// PurgeDeletedWebhooks hard-deletes webhooks once they were soft-deleted for longer than the retention period.
+// Until then, they can be restored.
+func PurgeDeletedWebhooks(ctx context.Context, repo Repository) {
+	ticker := time.NewTicker(webhookPurgeRate)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := purgeDeletedWebhooks(ctx, repo); err != nil {
+				log.Println("Error while purging deleted webhooks ", err)
+			}
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func purgeDeletedWebhooks(ctx context.Context, repo Repository) error {
+	var err error
+	repo = repo.WithContext(ctx)
+
+	var purged int64
+	if purged, err = repo.PurgeDeletedHooks(time.Now().Add(-WebhookRetention)); err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Println("purged", purged, "deleted webhooks")
+	}
+
+	return nil
+}
@@ -18,12 +18,30 @@ func idExtractMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func Router() chi.Router {
+func repositoryMiddleware(repo Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "repo", repo)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func requestRepository(r *http.Request) Repository {
+	repo := r.Context().Value("repo").(Repository)
+	return repo.WithContext(r.Context())
+}
+
+func Router(repo Repository) chi.Router {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Default().Handler)
+	if RateLimitRps > 0 {
+		r.Use(NewIpRateLimiter(RateLimitRps, RateLimitBurst, TrustedProxies).Middleware)
+	}
 	r.Use(middleware.Timeout(10 * time.Second))
+	r.Use(repositoryMiddleware(repo))
 
 	r.Route("/meta/webhooks", func(r chi.Router) {
 		r.Get("/twitter", handleGetMetaTwitterSubscriptions)
@@ -31,32 +49,56 @@ func Router() chi.Router {
 		r.Get("/almanax", handleGetMetaAlmanaxSubscriptions)
 	})
 
+	r.Route("/admin", func(r chi.Router) {
+		r.Route("/feeds", func(r chi.Router) {
+			r.With(requireScope(ScopeAdminRead)).Get("/", handleGetAdminFeeds)
+			r.Route("/{type}", func(r chi.Router) {
+				r.With(requireScope(ScopeFeedsWrite)).Post("/", handleCreateAdminFeed)
+				r.With(idExtractMiddleware).Route("/{id}", func(r chi.Router) {
+					r.With(requireScope(ScopeAdminRead)).Get("/", handleGetAdminFeed)
+					r.With(requireScope(ScopeFeedsWrite)).Put("/", handlePutAdminFeed)
+					r.With(requireScope(ScopeFeedsWrite)).Delete("/", handleDeleteAdminFeed)
+				})
+			})
+		})
+	})
+
 	r.Route("/webhooks", func(r chi.Router) {
 
 		r.Route("/rss", func(r chi.Router) {
-			r.Post("/", handleCreateRssHook)
+			r.With(optionalApiKey).Post("/", handleCreateRssHook)
+			r.Post("/preview", handlePreviewRss)
 			r.With(idExtractMiddleware).Route("/{id}", func(r chi.Router) {
 				r.Get("/", handleGetRss)
 				r.Delete("/", handleDeleteRss)
 				r.Put("/", handlePutRss)
+				r.Get("/deliveries", handleGetRssDeliveries)
+				r.Post("/restore", handleRestoreRss)
+				r.Post("/test", handleTestRss)
 			})
 		})
 
 		r.Route("/twitter", func(r chi.Router) {
-			r.Post("/", handleCreateTwitterHook)
+			r.With(optionalApiKey).Post("/", handleCreateTwitterHook)
 			r.With(idExtractMiddleware).Route("/{id}", func(r chi.Router) {
 				r.Get("/", handleGetTwitter)
 				r.Delete("/", handleDeleteTwitter)
 				r.Put("/", handlePutTwitter)
+				r.Get("/deliveries", handleGetTwitterDeliveries)
+				r.Post("/restore", handleRestoreTwitter)
 			})
 		})
 
 		r.Route("/almanax", func(r chi.Router) {
-			r.Post("/", handleCreateAlmanax)
+			r.With(optionalApiKey).Post("/", handleCreateAlmanax)
+			r.Post("/preview", handlePreviewAlmanax)
 			r.With(idExtractMiddleware).Route("/{id}", func(r chi.Router) {
 				r.Get("/", handleGetAlmanax)
 				r.Delete("/", handleDeleteAlmanaxHook)
 				r.Put("/", handlePutAlmanax)
+				r.Get("/deliveries", handleGetAlmanaxDeliveries)
+				r.Post("/restore", handleRestoreAlmanax)
+				r.Post("/test", handleTestAlmanax)
 			})
 		})
 
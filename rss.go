@@ -15,6 +15,7 @@ import (
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 
+	"github.com/google/uuid"
 	"github.com/mmcdole/gofeed"
 )
 
@@ -39,6 +40,21 @@ func handlePutRss(w http.ResponseWriter, r *http.Request) {
 	handlePutSocial(RSSWebhookType, w, r)
 }
 
+func handleGetRssDeliveries(w http.ResponseWriter, r *http.Request) {
+	metricsIncSocialCRUD(RSSWebhookType)
+	handleGetDeliveries(RSSWebhookType, w, r)
+}
+
+func handleRestoreRss(w http.ResponseWriter, r *http.Request) {
+	metricsIncSocialCRUD(RSSWebhookType)
+	handleRestoreHook(RSSWebhookType, w, r)
+}
+
+func handleTestRss(w http.ResponseWriter, r *http.Request) {
+	metricsIncSocialCRUD(RSSWebhookType)
+	handleTestHook(RSSWebhookType, w, r)
+}
+
 func handleCreateRssHook(w http.ResponseWriter, r *http.Request) {
 	metricsIncSocialCRUD(RSSWebhookType)
 	handleCreateSocial(RSSWebhookType, w, r)
@@ -76,50 +92,157 @@ func shortenAndRenderDescription(description string, maxLength int) (string, err
 
 // fire hook handlers
 
-func HandleTimeRss(socialFeed IFeed, state *RssState, _ time.Time, _ time.Duration, repo Repository) ([]RssSend, error) {
+const rssSeenItemsLimit = 200
+
+func fetchRssFeed(ctx context.Context, url string) (*gofeed.Feed, error) {
 	fp := gofeed.NewParser()
 	fp.UserAgent = "Mozilla/5.0 (Windows NT 6.1; rv:2.0b7) Gecko/20100101 Firefox/4.0b7"
-	var rssSends []RssSend
-	rssFeed, err := fp.ParseURL(socialFeed.GetRSSUrl())
+	return fp.ParseURLWithContext(url, ctx)
+}
+
+func (s *RssState) pendingFeedState() *FeedStateUpdate {
+	return s.pending
+}
+
+func (s *RssState) commitFeedState() {
+	if s.pending != nil {
+		s.SeenItems = s.pending.State.SeenItems
+		s.pending = nil
+	}
+}
+
+func HandleTimeRss(socialFeed IFeed, state *RssState, _ time.Time, _ time.Duration, repo Repository) ([]RssSend, error) {
+	rssFeed, err := fetchRssFeed(context.Background(), socialFeed.GetRSSUrl())
 	if err != nil {
 		return nil, err
 	}
 
+	return handleRssItems(socialFeed, state, rssFeed.Items, repo)
+}
+
+// rssItemKey identifies an item independent of its content, so edits and reordering don't make it look new.
+func rssItemKey(item *gofeed.Item) string {
+	if item.GUID != "" {
+		return "guid:" + item.GUID
+	}
+
+	if item.Link != "" {
+		return "link:" + item.Link
+	}
+
+	published := item.Published
+	if item.PublishedParsed != nil {
+		published = item.PublishedParsed.UTC().Format(time.RFC3339)
+	}
+	return "title:" + item.Title + "|" + published
+}
+
+func rssItemContentHash(item *gofeed.Item) (uint64, error) {
+	return hashstructure.Hash(struct {
+		Title       string
+		Description string
+		Content     string
+	}{
+		Title:       item.Title,
+		Description: item.Description,
+		Content:     item.Content,
+	}, hashstructure.FormatV2, nil)
+}
+
+// diffRssItems returns the items not seen before and the seen items with changed content. The state keeps the
+// latest rssSeenItemsLimit keys (at least the whole feed). An empty state only gets seeded.
+func diffRssItems(state *RssState, items []*gofeed.Item) ([]gofeed.Item, []gofeed.Item, bool) {
 	var newItems []gofeed.Item
-	if len(rssFeed.Items) == 0 {
-		return nil, nil
+	var updatedItems []gofeed.Item
+
+	seeding := len(state.SeenItems) == 0
+	seenHashes := make(map[string]uint64, len(state.SeenItems))
+	for _, seenItem := range state.SeenItems {
+		seenHashes[seenItem.Key] = seenItem.Hash
 	}
 
-	// build initial hash
-	if state.LastHash == 0 {
-		var itemHash uint64
-		if itemHash, err = hashstructure.Hash(rssFeed.Items[0], hashstructure.FormatV2, nil); err != nil {
-			log.Println("Error hashing item", err)
-			return nil, err
+	changed := seeding
+	polledKeys := NewSet[string]()
+	var polledItems []RssSeenItem
+	for _, item := range items {
+		key := rssItemKey(item)
+		if polledKeys.Has(key) {
+			continue
 		}
-		state.LastHash = itemHash
-		return nil, nil
-	}
+		polledKeys.Add(key)
 
-	var firstHash uint64 = 0
-	for _, item := range rssFeed.Items {
-		var itemHash uint64
-		if itemHash, err = hashstructure.Hash(item, hashstructure.FormatV2, nil); err != nil {
+		itemHash, err := rssItemContentHash(item)
+		if err != nil {
 			log.Println("Error hashing item", err)
 			continue
 		}
+		polledItems = append(polledItems, RssSeenItem{Key: key, Hash: itemHash})
+
+		seenHash, seen := seenHashes[key]
+		switch {
+		case seeding:
+		case !seen:
+			newItems = append(newItems, *item)
+			changed = true
+		case seenHash != itemHash:
+			updatedItems = append(updatedItems, *item)
+			changed = true
+		}
+	}
 
-		if firstHash == 0 {
-			firstHash = itemHash
+	// keep items that dropped out of the feed, so they are not sent again when they come back
+	for _, seenItem := range state.SeenItems {
+		if !polledKeys.Has(seenItem.Key) {
+			polledItems = append(polledItems, seenItem)
 		}
+	}
+
+	limit := max(rssSeenItemsLimit, polledKeys.Size())
+	if len(polledItems) > limit {
+		polledItems = polledItems[:limit]
+	}
+	state.SeenItems = polledItems
+
+	return newItems, updatedItems, changed
+}
+
+// handleRssItems compares the fetched items against the feed state. The new state is kept pending until the tick
+// enqueued its deliveries, so items published while the service was down are still delivered after a restart.
+func handleRssItems(socialFeed IFeed, state *RssState, items []*gofeed.Item, repo Repository) ([]RssSend, error) {
+	var err error
+	var rssSends []RssSend
 
-		if itemHash == state.LastHash {
-			break
+	if !state.restored {
+		var storedState RssState
+		var found bool
+		if storedState, found, err = repo.GetRssState(socialFeed.GetId()); err != nil {
+			return nil, err
 		}
+		if found {
+			state.SeenItems = storedState.SeenItems
+		}
+		state.restored = true
+	}
 
-		newItems = append(newItems, *item)
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	// the state only advances once the sends are enqueued, so items are polled again when anything fails until then
+	next := RssState{SeenItems: state.SeenItems}
+	newItems, updatedItems, changed := diffRssItems(&next, items)
+	state.pending = nil
+	if changed {
+		state.pending = &FeedStateUpdate{FeedId: socialFeed.GetId(), State: next}
+	}
+
+	if !RssSendUpdates {
+		updatedItems = nil
+	}
+
+	if len(newItems) == 0 && len(updatedItems) == 0 {
+		return nil, nil
 	}
-	state.LastHash = firstHash
 
 	var subbedWebhooks []HasIdBlackWhiteList[string]
 	if subbedWebhooks, err = repo.GetRSSSubsForFeed(socialFeed); err != nil {
@@ -131,15 +254,48 @@ func HandleTimeRss(socialFeed IFeed, state *RssState, _ time.Time, _ time.Durati
 		return nil, nil
 	}
 
+	// the batch sender doesn't return Discord's answer, so without message ids updates are posted as new messages
+	editMessages := !SendBatchEnabled
+
 	for _, item := range newItems {
 		webhooksToSend := filterByBlackWhitelist(subbedWebhooks, item.Description)
 		sendHooksTotal.Add(float64(len(webhooksToSend)))
 		sendHooksRss.Add(float64(len(webhooksToSend)))
 
+		var itemKey string
+		if editMessages {
+			itemKey = rssItemKey(&item)
+		}
+
+		rssSends = append(rssSends, RssSend{
+			Item:     item,
+			ItemKey:  itemKey,
+			Webhooks: webhooksToSend,
+			Feed:     socialFeed,
+		})
+	}
+
+	for _, item := range updatedItems {
+		webhooksToSend := filterByBlackWhitelist(subbedWebhooks, item.Description)
+		sendHooksTotal.Add(float64(len(webhooksToSend)))
+		sendHooksRss.Add(float64(len(webhooksToSend)))
+
+		var itemKey string
+		var messages map[uuid.UUID]DiscordMessageRef
+		if editMessages {
+			itemKey = rssItemKey(&item)
+			if messages, err = repo.GetDiscordMessages(itemKey, Map(webhooksToSend, IHook.GetId)); err != nil {
+				return nil, err
+			}
+		}
+
 		rssSends = append(rssSends, RssSend{
 			Item:     item,
+			ItemKey:  itemKey,
+			Updated:  true,
 			Webhooks: webhooksToSend,
 			Feed:     socialFeed,
+			Messages: messages,
 		})
 	}
 
@@ -158,22 +314,64 @@ func generateUsernameRss(feed IFeed) string {
 	return game + " " + newsType
 }
 
+// rssFeedLanguage is the language code in the feed name, like "fr" in "dofus3-fr-official-news".
+func rssFeedLanguage(feed IFeed) string {
+	nameParts := strings.Split(feed.GetFeedName(), "-")
+	if len(nameParts) < 2 {
+		return ""
+	}
+	return nameParts[1]
+}
+
+func generateUpdatedLabelRss(feed IFeed) string {
+	switch rssFeedLanguage(feed) {
+	case "fr":
+		return "Mise à jour"
+	case "es":
+		return "Actualización"
+	case "pt":
+		return "Atualização"
+	case "de":
+		return "Aktualisierung"
+	case "it":
+		return "Aggiornamento"
+	default:
+		return "Update"
+	}
+}
+
 func BuildDiscordHookRss(rssHookBuild RssSend) ([]PreparedHook, error) {
-	var discordWebhook DiscordWebhook
 	var res []PreparedHook
 
+	updatedTitle := rssHookBuild.Item.Title
+	if rssHookBuild.Updated {
+		updatedTitle = "[" + generateUpdatedLabelRss(rssHookBuild.Feed) + "] " + updatedTitle
+	}
+
 	optImage := findImageUrl(rssHookBuild.Item.Description)
 	for _, webhook := range rssHookBuild.Webhooks {
+		// the posted message is edited in place, so it doesn't need to be marked as updated
+		title := updatedTitle
+		callback := discordThreadCallback(webhook.GetCallback(), webhook.GetDiscordThread())
+		message, posted := rssHookBuild.Messages[webhook.GetId()]
+		if posted {
+			title = rssHookBuild.Item.Title
+			callback = discordMessageCallback(webhook.GetCallback(), webhook.GetDiscordThread(), message)
+		} else if rssHookBuild.ItemKey != "" {
+			callback = discordWaitCallback(callback)
+		}
+
 		shortenedText, err := shortenAndRenderDescription(rssHookBuild.Item.Description, webhook.GetPreviewLength())
 		if err != nil {
 			return nil, err
 		}
 
+		var discordWebhook DiscordWebhook
 		discordWebhook.AvatarUrl = "https://discord.dofusdude.com/ankama_rss_logo.jpg"
 		discordWebhook.Username = generateUsernameRss(rssHookBuild.Feed)
 		discordWebhook.Embeds = []DiscordEmbed{
 			{
-				Title: &rssHookBuild.Item.Title,
+				Title: &title,
 				Color: 3684408,
 				Url:   &rssHookBuild.Item.Link,
 			},
@@ -189,23 +387,104 @@ func BuildDiscordHookRss(rssHookBuild RssSend) ([]PreparedHook, error) {
 			discordWebhook.Embeds[0].Description = &shortenedText
 		}
 
+		webhook.GetDiscordStyle().apply(&discordWebhook)
+
+		if source := webhook.GetTemplate(); source != nil {
+			if discordWebhook, err = renderRssTemplate(*source, rssHookBuild, webhook, discordWebhook); err != nil {
+				return nil, err
+			}
+		}
+
+		forumThread := webhook.GetDiscordThread()
+		if posted {
+			forumThread = nil // the forum post exists already, only its message is edited
+		}
+		applyDiscordForumPost(&discordWebhook, forumThread, title, rssHookBuild.Feed)
+
 		jsonBody, err := json.Marshal(discordWebhook)
 		if err != nil {
 			return nil, err
 		}
 
 		res = append(res, PreparedHook{
-			Callback: webhook.GetCallback(),
-			Body:     string(jsonBody),
+			WebhookId: webhook.GetId(),
+			Callback:  callback,
+			Body:      string(jsonBody),
+			ItemKey:   rssHookBuild.ItemKey,
 		})
 	}
 
 	return res, nil
 }
 
-func ListenRss(ctx context.Context, feed IFeed) {
-	var state RssState
-	state.LastHash = 0
+// renderRssTemplate renders the template of the webhook for the item. Templates that fail on the item are logged and
+// the message falls back to the built-in one, so other webhooks still get theirs.
+func renderRssTemplate(source string, rssHookBuild RssSend, webhook IHook, fallback DiscordWebhook) (DiscordWebhook, error) {
+	item, err := newJsonEventItemRss(rssHookBuild, webhook)
+	if err != nil {
+		return DiscordWebhook{}, err
+	}
+
+	data := DiscordTemplateData{
+		Feed: rssHookBuild.Feed.GetFeedName(),
+		Item: item,
+	}
+
+	rendered, err := renderDiscordTemplate(source, rssTemplateDate(rssHookBuild.Feed), fallback, data)
+	if err != nil {
+		log.Println("could not render template of webhook", webhook.GetId(), err)
+		return fallback, nil
+	}
+
+	return rendered, nil
+}
+
+// BuildHookRss builds every webhook in the format it was created with.
+func BuildHookRss(rssHookBuild RssSend) ([]PreparedHook, error) {
+	var res []PreparedHook
 
-	Listen(ctx, RssPollingRate, feed, &state, HandleTimeRss, BuildDiscordHookRss)
+	discordBuild := rssHookBuild
+	discordBuild.Webhooks = nil
+	for _, webhook := range rssHookBuild.Webhooks {
+		switch webhook.GetFormat() {
+		case JsonFormat:
+			hook, err := buildJsonHookRss(rssHookBuild, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case SlackFormat:
+			hook, err := buildSlackHookRss(rssHookBuild, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case TelegramFormat:
+			hook, err := buildTelegramHookRss(rssHookBuild, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case MatrixFormat:
+			hook, err := buildMatrixHookRss(rssHookBuild, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		default:
+			discordBuild.Webhooks = append(discordBuild.Webhooks, webhook)
+		}
+	}
+
+	discordHooks, err := BuildDiscordHookRss(discordBuild)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(res, discordHooks...), nil
+}
+
+func ListenRss(ctx context.Context, repo Repository, feed IFeed) {
+	var state RssState
+	Listen(ctx, repo, RssPollingRate, feed, &state, HandleTimeRss, BuildHookRss)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/mmcdole/gofeed"
@@ -9,10 +10,13 @@ import (
 	jsonpath "github.com/steinfletcher/apitest-jsonpath"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFilterMarkdownImages(t *testing.T) {
@@ -165,6 +169,79 @@ func TestShortenAndRenderDescription(t *testing.T) {
 	assert.Equal(t, " ...", markdown[len(markdown)-4:])
 }
 
+func TestRssItemKey(t *testing.T) {
+	assert.Equal(t, "guid:123", rssItemKey(&gofeed.Item{GUID: "123", Link: "https://www.dofus.com/1"}))
+	assert.Equal(t, "link:https://www.dofus.com/1", rssItemKey(&gofeed.Item{Link: "https://www.dofus.com/1", Title: "News"}))
+	assert.Equal(t, "title:News|Sun, 02 Oct 2022 13:42:31 +0200", rssItemKey(&gofeed.Item{Title: "News", Published: "Sun, 02 Oct 2022 13:42:31 +0200"}))
+}
+
+func TestDiffRssItems(t *testing.T) {
+	first := &gofeed.Item{GUID: "1", Title: "First", Description: "first"}
+	second := &gofeed.Item{GUID: "2", Title: "Second", Description: "second"}
+	third := &gofeed.Item{GUID: "3", Title: "Third", Description: "third"}
+
+	var state RssState
+	newItems, updatedItems, changed := diffRssItems(&state, []*gofeed.Item{second, first})
+	assert.True(t, changed)
+	assert.Len(t, newItems, 0)
+	assert.Len(t, updatedItems, 0)
+	assert.Len(t, state.SeenItems, 2)
+
+	// reordering is not new
+	newItems, updatedItems, changed = diffRssItems(&state, []*gofeed.Item{first, second})
+	assert.False(t, changed)
+	assert.Len(t, newItems, 0)
+	assert.Len(t, updatedItems, 0)
+
+	newItems, updatedItems, changed = diffRssItems(&state, []*gofeed.Item{third, second, first})
+	assert.True(t, changed)
+	assert.Len(t, newItems, 1)
+	assert.Equal(t, "Third", newItems[0].Title)
+	assert.Len(t, updatedItems, 0)
+
+	// an edit of an older item is an update, not a flood of everything above it
+	editedFirst := &gofeed.Item{GUID: "1", Title: "First", Description: "first, fixed typo"}
+	newItems, updatedItems, changed = diffRssItems(&state, []*gofeed.Item{third, second, editedFirst})
+	assert.True(t, changed)
+	assert.Len(t, newItems, 0)
+	assert.Len(t, updatedItems, 1)
+	assert.Equal(t, "first, fixed typo", updatedItems[0].Description)
+
+	// a vanished item is remembered
+	newItems, _, _ = diffRssItems(&state, []*gofeed.Item{third})
+	assert.Len(t, newItems, 0)
+	newItems, _, _ = diffRssItems(&state, []*gofeed.Item{third, second})
+	assert.Len(t, newItems, 0)
+}
+
+func TestDiffRssItemsLimit(t *testing.T) {
+	var state RssState
+	for i := 0; i < rssSeenItemsLimit+10; i++ {
+		diffRssItems(&state, []*gofeed.Item{{GUID: fmt.Sprintf("%d", i)}})
+	}
+	assert.Len(t, state.SeenItems, rssSeenItemsLimit)
+	assert.Equal(t, fmt.Sprintf("guid:%d", rssSeenItemsLimit+9), state.SeenItems[0].Key)
+}
+
+func TestHandleRssItemsKeepsStatePending(t *testing.T) {
+	state := RssState{restored: true}
+	sends, err := handleRssItems(&RssFeed{Id: 1}, &state, []*gofeed.Item{{GUID: "1"}, {GUID: "2"}}, Repository{})
+	assert.Nil(t, err)
+	assert.Nil(t, sends)
+	assert.Empty(t, state.SeenItems)
+	assert.Equal(t, uint64(1), state.pendingFeedState().FeedId)
+	assert.Len(t, state.pendingFeedState().State.SeenItems, 2)
+
+	// a failed tick polls the same items again against the old state
+	_, err = handleRssItems(&RssFeed{Id: 1}, &state, []*gofeed.Item{{GUID: "1"}, {GUID: "2"}}, Repository{})
+	assert.Nil(t, err)
+	assert.Empty(t, state.SeenItems)
+
+	state.commitFeedState()
+	assert.Len(t, state.SeenItems, 2)
+	assert.Nil(t, state.pendingFeedState())
+}
+
 type RssTestSuite struct {
 	suite.Suite
 	db           Repository
@@ -182,13 +259,13 @@ func (suite *RssTestSuite) Test_Feeds_Db() {
 func (suite *RssTestSuite) SetupSuite() {
 	ReadEnvs()
 
-	var repo Repository
-	if err := repo.Init(context.Background()); err != nil {
+	pool, err := NewPool(context.Background())
+	if err != nil {
 		suite.T().Fatal(err)
 	}
 
-	suite.db = repo
-	suite.sut = httptest.NewServer(Router())
+	suite.db = NewRepository(context.Background(), pool)
+	suite.sut = httptest.NewServer(Router(suite.db))
 	suite.discordCheck = append(suite.discordCheck, apitest.NewMock().
 		Get("https://discord.com/api/webhooks/123/abc").
 		RespondWith().
@@ -218,7 +295,7 @@ func (suite *RssTestSuite) TearDownTest() {
 
 func (suite *RssTestSuite) Test_Feeds() {
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/meta/webhooks/rss").
 		Expect(suite.T()).
 		Status(http.StatusOK).
@@ -243,7 +320,7 @@ func (suite *RssTestSuite) Test_Feeds() {
 func (suite *RssTestSuite) Test_GetFeeds() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -274,7 +351,7 @@ func (suite *RssTestSuite) Test_GetFeeds() {
 func (suite *RssTestSuite) Test_CRUD_Create() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -294,7 +371,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[1]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc1", // callback ok but no subscriptions
@@ -306,7 +383,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[1]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc3",
@@ -320,7 +397,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc", // double callback
@@ -335,7 +412,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[3]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc3",
@@ -349,7 +426,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 		End()
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		Expect(suite.T()).
 		Status(http.StatusBadRequest). // no body
@@ -357,7 +434,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[5]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc5",
@@ -390,7 +467,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[6]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc6",
@@ -421,7 +498,7 @@ func (suite *RssTestSuite) Test_CRUD_Create() {
 func (suite *RssTestSuite) Test_CRUD_Create_And_Get() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -443,7 +520,7 @@ func (suite *RssTestSuite) Test_CRUD_Create_And_Get() {
 	assert.Nil(suite.T(), err)
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/webhooks/rss/" + id.String()).
 		Expect(suite.T()).
 		Status(http.StatusOK).
@@ -461,7 +538,7 @@ func (suite *RssTestSuite) Test_CRUD_Create_And_Get() {
 func (suite *RssTestSuite) Test_CRUD_Delete() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -483,24 +560,180 @@ func (suite *RssTestSuite) Test_CRUD_Delete() {
 	assert.Nil(suite.T(), err)
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Delete("/webhooks/rss/" + id.String()).
 		Expect(suite.T()).
 		Status(http.StatusNoContent).
 		End()
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/webhooks/rss/" + id.String()).
 		Expect(suite.T()).
 		Status(http.StatusNotFound).
 		End()
 }
 
+func (suite *RssTestSuite) Test_TestMessage_UnknownHook() {
+	id := uuid.New()
+	for i := 0; i < 5; i++ {
+		apitest.New().
+			Handler(Router(suite.db)).
+			Post("/webhooks/rss/" + id.String() + "/test").
+			Expect(suite.T()).
+			Status(http.StatusNotFound).
+			End()
+	}
+
+	testMessageLimiter.mu.Lock()
+	defer testMessageLimiter.mu.Unlock()
+	assert.NotContains(suite.T(), testMessageLimiter.buckets, id.String())
+}
+
+func (suite *RssTestSuite) Test_TestMessage() {
+	feedXml, err := os.ReadFile("testdata/fusionNewsItem.xml")
+	assert.NoError(suite.T(), err)
+
+	var mu sync.Mutex
+	var discordBodies []string
+	discordStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(feedXml)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		discordBodies = append(discordBodies, string(body))
+		status := discordStatus
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusNotFound {
+			_, _ = w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "1", "channel_id": "2"}`))
+	}))
+	defer server.Close()
+
+	// the feed and Discord are served by the test server
+	var feedUrl string
+	assert.Nil(suite.T(), suite.db.conn.QueryRow(context.Background(), "select url from rss_feeds where api_readable_id = 'dofus3-fr-official-news'").Scan(&feedUrl))
+	_, err = suite.db.conn.Exec(context.Background(), "update rss_feeds set url = $1 where api_readable_id = 'dofus3-fr-official-news'", server.URL+"/rss")
+	assert.Nil(suite.T(), err)
+	defer func() {
+		_, _ = suite.db.conn.Exec(context.Background(), "update rss_feeds set url = $1 where api_readable_id = 'dofus3-fr-official-news'", feedUrl)
+	}()
+
+	apitest.New().
+		Mocks(suite.discordCheck[0]).
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		JSON(SocialHookCreate{
+			Callback:      "https://discord.com/api/webhooks/123/abc",
+			Subscriptions: []string{"dofus3-fr-official-news"},
+			Format:        "discord",
+		}).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		End()
+
+	id, err := testutilGetlastinsertedwebhookid()
+	assert.Nil(suite.T(), err)
+	_, err = suite.db.conn.Exec(context.Background(), "update webhooks set callback = $1 where id = $2", server.URL+"/api/webhooks/123/abc", id)
+	assert.Nil(suite.T(), err)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/test").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Chain().
+			Equal("$.ok", true).
+			Equal("$.status_code", float64(http.StatusOK)).
+			End(),
+		).
+		End()
+
+	mu.Lock()
+	assert.Len(suite.T(), discordBodies, 1)
+	var message DiscordWebhook
+	assert.Nil(suite.T(), json.Unmarshal([]byte(discordBodies[0]), &message))
+	assert.NotEmpty(suite.T(), message.Embeds)
+	assert.Contains(suite.T(), message.Embeds[len(message.Embeds)-1].Footer.Text, discordTestFooter)
+	discordStatus = http.StatusNotFound
+	mu.Unlock()
+
+	// the status code of Discord is relayed
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/test").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Chain().
+			Equal("$.ok", false).
+			Equal("$.status_code", float64(http.StatusNotFound)).
+			Present("$.error").
+			End(),
+		).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/test").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/test").
+		Expect(suite.T()).
+		Status(http.StatusTooManyRequests).
+		HeaderPresent("Retry-After").
+		End()
+
+	mu.Lock()
+	assert.Len(suite.T(), discordBodies, 3)
+	mu.Unlock()
+
+	// test messages are only sent to Discord
+	apitest.New().
+		Mocks(apitest.NewMock().
+			Post("https://hooks.slack.com/services/T000/B000/XXXX").
+			RespondWith().
+			Status(http.StatusBadRequest).
+			End()).
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		JSON(SocialHookCreate{
+			Callback:      "https://hooks.slack.com/services/T000/B000/XXXX",
+			Subscriptions: []string{"dofus3-fr-official-news"},
+			Format:        SlackFormat,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		End()
+
+	slackId, err := testutilGetlastinsertedwebhookid()
+	assert.Nil(suite.T(), err)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + slackId.String() + "/test").
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		Body("Test messages are only available for Discord webhooks.\n").
+		End()
+}
+
 func (suite *RssTestSuite) Test_CRUD_Create_And_Update() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/rss").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -525,7 +758,7 @@ func (suite *RssTestSuite) Test_CRUD_Create_And_Update() {
 	assert.Nil(suite.T(), err)
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/rss/" + id.String()).
 		JSON(SocialWebhookPut{
 			Subscriptions: []string{
@@ -553,7 +786,7 @@ func (suite *RssTestSuite) Test_CRUD_Create_And_Update() {
 		End()
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/rss/" + id.String()).
 		JSON(SocialWebhookPut{
 			Subscriptions: []string{
@@ -571,6 +804,338 @@ func (suite *RssTestSuite) Test_CRUD_Create_And_Update() {
 		End()
 }
 
+func (suite *RssTestSuite) Test_FeedState_Restart() {
+	apitest.New().
+		Mocks(suite.discordCheck[0]).
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		JSON(SocialHookCreate{
+			Callback: "https://discord.com/api/webhooks/123/abc",
+			Subscriptions: []string{
+				"dofus3-fr-official-news",
+			},
+			Format: "discord",
+		}).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		End()
+
+	feeds, err := suite.db.GetRssFeeds([]uint64{1})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), feeds, 1)
+
+	file, err := os.ReadFile("testdata/fusionNewsItem.xml")
+	assert.NoError(suite.T(), err)
+	fp := gofeed.NewParser()
+	rssFeed, err := fp.ParseString(string(file))
+	assert.NoError(suite.T(), err)
+
+	var state RssState
+	sends, err := handleRssItems(feeds[0], &state, rssFeed.Items, suite.db)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), sends)
+	assert.Empty(suite.T(), state.SeenItems)
+
+	// nothing is stored before the tick enqueued its deliveries
+	_, found, err := suite.db.GetRssState(feeds[0].GetId())
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), found)
+
+	_, err = suite.db.EnqueueOutboxWithState(nil, OutboxLease, state.pendingFeedState())
+	assert.Nil(suite.T(), err)
+	state.commitFeedState()
+
+	storedState, found, err := suite.db.GetRssState(feeds[0].GetId())
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), found)
+	assert.Equal(suite.T(), state.SeenItems, storedState.SeenItems)
+
+	// a new item is published while the service is down
+	items := append([]*gofeed.Item{{
+		Title:       "Maintenance",
+		Link:        "https://www.dofus.com/fr/mmorpg/actualites/news/1",
+		Description: "Maintenance ce matin.",
+	}}, rssFeed.Items...)
+
+	var restartedState RssState
+	sends, err = handleRssItems(feeds[0], &restartedState, items, suite.db)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), sends, 1)
+	assert.Equal(suite.T(), "Maintenance", sends[0].Item.Title)
+	assert.Len(suite.T(), sends[0].Webhooks, 1)
+}
+
+func (suite *RssTestSuite) Test_Deliveries() {
+	apitest.New().
+		Mocks(suite.discordCheck[0]).
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		JSON(SocialHookCreate{
+			Callback: "https://discord.com/api/webhooks/123/abc",
+			Subscriptions: []string{
+				"dofus3-fr-official-news",
+			},
+			Format: "discord",
+		}).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		End()
+
+	id, err := testutilGetlastinsertedwebhookid()
+	assert.Nil(suite.T(), err)
+
+	hook := PreparedHook{WebhookId: id, FeedId: 1}
+	for i := 0; i < 3; i++ {
+		assert.Nil(suite.T(), suite.db.RecordDeliveryAttempt(hook, SendCallbackReturn{Ok: true, StatusCode: http.StatusNoContent, Attempts: 1}))
+	}
+	assert.Nil(suite.T(), suite.db.RecordDeliveryAttempt(hook, SendCallbackReturn{Err: fmt.Errorf("connection reset"), Attempts: 4}))
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/"+id.String()+"/deliveries").
+		Query("page_size", "2").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Chain().
+			Equal("$.total", float64(4)).
+			Equal("$.page", float64(1)).
+			Equal("$.deliveries[0].ok", false).
+			Equal("$.deliveries[0].error", "connection reset").
+			Equal("$.deliveries[0].attempts", float64(4)).
+			Equal("$.deliveries[1].status_code", float64(http.StatusNoContent)).
+			Equal("$.deliveries[1].feed", "dofus3-fr-official-news").
+			End(),
+		).
+		Assert(jsonpath.Len("$.deliveries", 2)).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/"+id.String()+"/deliveries").
+		Query("page", "2").
+		Query("page_size", "3").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Len("$.deliveries", 1)).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/"+id.String()+"/deliveries").
+		Query("page_size", "1000").
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/" + uuid.New().String() + "/deliveries").
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	// the log stays readable after the hook got disabled for failing
+	disabled, err := suite.db.RecordWebhookFailure(id, "target responded with status 404", 1, -time.Minute)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), disabled)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/" + id.String() + "/deliveries").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal("$.total", float64(4))).
+		End()
+}
+
+func (suite *RssTestSuite) Test_CRUD_Restore() {
+	apitest.New().
+		Mocks(suite.discordCheck[0]).
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		JSON(SocialHookCreate{
+			Callback: "https://discord.com/api/webhooks/123/abc",
+			Subscriptions: []string{
+				"dofus3-fr-official-news",
+			},
+			Format: "discord",
+		}).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		End()
+
+	id, err := testutilGetlastinsertedwebhookid()
+	assert.Nil(suite.T(), err)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/restore").
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Delete("/webhooks/rss/" + id.String()).
+		Expect(suite.T()).
+		Status(http.StatusNoContent).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/twitter/" + id.String() + "/restore").
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+
+	apitest.New().
+		Mocks(suite.discordCheck[0]).
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/restore").
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Chain().
+			Equal("$.id", id.String()).
+			Contains("$.subscriptions", "dofus3-fr-official-news").
+			Equal("$.failure_count", float64(0)).
+			End(),
+		).
+		End()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/" + id.String()).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		End()
+
+	// deleted for longer than the retention period
+	assert.Nil(suite.T(), suite.db.DeleteHook(id))
+	purged, err := suite.db.PurgeDeletedHooks(time.Now().Add(time.Minute))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), purged)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/" + id.String() + "/restore").
+		Expect(suite.T()).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func (suite *RssTestSuite) Test_CRUD_Create_Json() {
+	newHook := SocialHookCreate{
+		Callback: "https://bots.example.com/hooks",
+		Subscriptions: []string{
+			"dofus3-fr-official-news",
+		},
+		Format: JsonFormat,
+	}
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		JSON(newHook).
+		Expect(suite.T()).
+		Status(http.StatusUnauthorized). // custom targets need a key
+		End()
+
+	readKey, err := generateApiKey()
+	assert.Nil(suite.T(), err)
+	_, err = suite.db.CreateApiKey("test-read", hashApiKey(readKey), []string{ScopeAdminRead})
+	assert.Nil(suite.T(), err)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		Header("Authorization", "Bearer "+readKey).
+		JSON(newHook).
+		Expect(suite.T()).
+		Status(http.StatusForbidden).
+		End()
+
+	key, err := generateApiKey()
+	assert.Nil(suite.T(), err)
+	_, err = suite.db.CreateApiKey("test-targets", hashApiKey(key), []string{ScopeTargetsCustom})
+	assert.Nil(suite.T(), err)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		Header("Authorization", "Bearer "+key).
+		JSON(SocialHookCreate{
+			Callback:      "http://bots.example.com/hooks", // not https
+			Subscriptions: newHook.Subscriptions,
+			Format:        JsonFormat,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusBadRequest).
+		End()
+
+	var created SocialWebhookDTO
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss").
+		Header("Authorization", "Bearer "+key).
+		JSON(newHook).
+		Expect(suite.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Chain().
+			Equal("$.format", JsonFormat).
+			Present("$.secret").
+			End(),
+		).
+		End().
+		JSON(&created)
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Get("/webhooks/rss/" + created.Id.String()).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.NotPresent("$.secret")).
+		End()
+
+	hook, err := suite.db.GetSocialHook(RSSWebhookType, created.Id)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), *created.Secret, hook.(RssWebhook).GetSecret())
+}
+
+func (suite *RssTestSuite) Test_Preview() {
+	file, err := os.ReadFile("testdata/fusionNewsItem.xml")
+	assert.Nil(suite.T(), err)
+	rssFeed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write(file)
+	}))
+	defer rssFeed.Close()
+
+	feedId, err := suite.db.CreateFeed(RSSWebhookType, AdminFeedPost{Name: "dofus2-fr-preview-news", Url: rssFeed.URL})
+	assert.Nil(suite.T(), err)
+	defer func() {
+		_ = suite.db.DeleteFeed(feedId)
+	}()
+
+	apitest.New().
+		Handler(Router(suite.db)).
+		Post("/webhooks/rss/preview").
+		JSON(SocialHookCreate{
+			Subscriptions: []string{"dofus2-fr-preview-news"},
+			Format:        DiscordFormat,
+		}).
+		Expect(suite.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Chain().
+			Equal("$.username", "Dofus2 News").
+			Equal("$.embeds[0].title", "La fusion des serveurs DOFUS").
+			End(),
+		).
+		End()
+
+	_, err = testutilGetlastinsertedwebhookid()
+	assert.NotNil(suite.T(), err)
+}
+
 func TestRssTestSuite(t *testing.T) {
 	suite.Run(t, new(RssTestSuite))
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Sender delivers prepared hooks and reports the outcome for each one, in the same order.
+type Sender interface {
+	Send(ctx context.Context, hooks []PreparedHook) []SendCallbackReturn
+}
+
+var webhookSender Sender = NewDirectSender(webhookClient)
+
+// DirectSender posts every hook itself, concurrently.
+type DirectSender struct {
+	client *WebhookClient
+}
+
+func NewDirectSender(client *WebhookClient) *DirectSender {
+	return &DirectSender{client: client}
+}
+
+func (s *DirectSender) Send(ctx context.Context, hooks []PreparedHook) []SendCallbackReturn {
+	callbackReturns := make([]SendCallbackReturn, len(hooks))
+	var wg sync.WaitGroup
+	for i, hook := range hooks {
+		wg.Add(1)
+		go func(i int, hook PreparedHook) {
+			defer wg.Done()
+			callbackReturns[i] = s.client.Deliver(ctx, hook)
+		}(i, hook)
+	}
+	wg.Wait()
+
+	return callbackReturns
+}
+
+// BatchSender hands all hooks to an external sender service in one request. The service answers with the
+// callbacks it could not deliver.
+type BatchSender struct {
+	url    string
+	client *http.Client
+}
+
+func NewBatchSender(url string) *BatchSender {
+	return &BatchSender{
+		url:    url,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *BatchSender) Send(ctx context.Context, hooks []PreparedHook) []SendCallbackReturn {
+	callbackReturns := make([]SendCallbackReturn, len(hooks))
+	if len(hooks) == 0 {
+		return callbackReturns
+	}
+
+	var pack WebhookJobs
+	for _, hook := range hooks {
+		job := WebhookJob{
+			Url:    deliveryUrl(hook),
+			Method: deliveryMethod(hook.Callback),
+			Body:   hook.Body,
+		}
+		if hook.Secret != "" {
+			job.Headers = signBody(hook.Secret, hook.Body, time.Now())
+		}
+		if authorization := deliveryAuthorization(hook); authorization != "" {
+			if job.Headers == nil {
+				job.Headers = make(map[string]string)
+			}
+			job.Headers["Authorization"] = authorization
+		}
+		pack.Jobs = append(pack.Jobs, job)
+	}
+
+	started := time.Now()
+	statusCode, notWorked, err := s.send(ctx, pack)
+	latency := time.Since(started)
+
+	failed := NewSet[string]()
+	for _, callback := range notWorked {
+		failed.Add(callback)
+	}
+
+	for i, hook := range hooks {
+		callbackReturns[i] = SendCallbackReturn{
+			Callback:   hook.Callback,
+			StatusCode: statusCode,
+			Attempts:   1,
+			Latency:    latency,
+		}
+
+		switch {
+		case err != nil:
+			// the batch as a whole failed, so try again later
+			callbackReturns[i].Err = err
+		case failed.Has(pack.Jobs[i].Url):
+			// the service does not say why, so the outbox retries it and it does not count toward disabling the hook
+			callbackReturns[i].StatusCode = 0
+			callbackReturns[i].Err = errors.New("not delivered by the batch sender")
+		default:
+			callbackReturns[i].Ok = true
+		}
+	}
+
+	return callbackReturns
+}
+
+func (s *BatchSender) send(ctx context.Context, pack WebhookJobs) (int, []string, error) {
+	marshal, err := json.Marshal(pack)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewBuffer(marshal))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			log.Println(err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil, fmt.Errorf("batch sender returned status code %d", resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+
+	if len(bodyBytes) == 0 {
+		return resp.StatusCode, nil, errors.New("batch sender returned empty body")
+	}
+
+	var notWorkedUrls []string
+	if err = json.Unmarshal(bodyBytes, &notWorkedUrls); err != nil {
+		return resp.StatusCode, nil, err
+	}
+
+	return resp.StatusCode, notWorkedUrls, nil
+}
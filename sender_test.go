@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchSender(t *testing.T) {
+	var received WebhookJobs
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`["https://discord.com/api/webhooks/2/b"]`))
+	}))
+	defer server.Close()
+
+	hooks := []PreparedHook{
+		{Callback: "https://discord.com/api/webhooks/1/a", Body: `{"content": "a"}`},
+		{Callback: "https://discord.com/api/webhooks/2/b", Body: `{"content": "b"}`},
+	}
+	res := NewBatchSender(server.URL).Send(context.Background(), hooks)
+
+	assert.Len(t, received.Jobs, 2)
+	assert.Equal(t, "https://discord.com/api/webhooks/1/a", received.Jobs[0].Url)
+	assert.Equal(t, `{"content": "a"}`, received.Jobs[0].Body)
+
+	assert.Len(t, res, 2)
+	assert.True(t, res[0].Ok)
+	assert.False(t, res[1].Ok)
+	// a failed callback may only be a Discord outage, so it is not permanent
+	assert.False(t, res[1].Permanent)
+	assert.Zero(t, res[1].StatusCode)
+	assert.EqualError(t, res[1].Err, "not delivered by the batch sender")
+}
+
+func TestBatchSenderUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	res := NewBatchSender(server.URL).Send(context.Background(), []PreparedHook{
+		{Callback: "https://discord.com/api/webhooks/1/a", Body: "{}"},
+	})
+
+	assert.Len(t, res, 1)
+	assert.False(t, res[0].Ok)
+	assert.False(t, res[0].Permanent)
+	assert.NotNil(t, res[0].Err)
+	assert.Equal(t, http.StatusServiceUnavailable, res[0].StatusCode)
+}
+
+func TestDirectSender(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	res := NewDirectSender(NewWebhookClient(testRetryPolicy)).Send(context.Background(), []PreparedHook{
+		{Callback: server.URL + "/ok", Body: "{}"},
+		{Callback: server.URL + "/gone", Body: "{}"},
+	})
+
+	assert.Len(t, res, 2)
+	assert.True(t, res[0].Ok)
+	assert.Equal(t, server.URL+"/ok", res[0].Callback)
+	assert.True(t, res[1].Permanent)
+}
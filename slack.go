@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dofusdude/dodugo"
+)
+
+const (
+	slackHeaderLimit  = 150
+	slackSectionLimit = 3000
+)
+
+var (
+	slackLinkRegex    = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)\)`)
+	slackBoldRegex    = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	slackItalicRegex  = regexp.MustCompile(`\*([^*\n]+)\*`)
+	slackHeadingRegex = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+)
+
+func isSlackWebhookUrl(url string) bool {
+	return strings.HasPrefix(url, "https://hooks.slack.com/services/")
+}
+
+// isSlackWebhook checks the URL like isDiscordWebhook does. Slack has no GET for incoming webhooks, so it posts an
+// empty message instead: Slack refuses the payload with a 400 for existing hooks and with 403 or 404 for unknown ones.
+func isSlackWebhook(url string) bool {
+	if !isSlackWebhookUrl(url) {
+		return false
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	response, err := client.Post(url, "application/json", bytes.NewBufferString("{}"))
+	if err != nil {
+		return false
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	return response.StatusCode == http.StatusBadRequest || response.StatusCode == http.StatusOK
+}
+
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// markdownToSlack converts the markdown from shortenAndRenderDescription to Slack's mrkdwn.
+func markdownToSlack(markdown string) string {
+	text := escapeSlack(markdown)
+	text = slackHeadingRegex.ReplaceAllString(text, "**$1**")
+	text = slackLinkRegex.ReplaceAllString(text, "<$2|$1>")
+	text = slackBoldRegex.ReplaceAllString(text, "\x00$2\x00")
+	text = slackItalicRegex.ReplaceAllString(text, "_${1}_")
+	return strings.ReplaceAll(text, "\x00", "*")
+}
+
+func slackHeader(text string) SlackBlock {
+	return SlackBlock{
+		Type: "header",
+		Text: &SlackText{Type: "plain_text", Text: TruncateText(text, slackHeaderLimit)},
+	}
+}
+
+func slackSection(mrkdwn string) SlackBlock {
+	return SlackBlock{
+		Type: "section",
+		Text: &SlackText{Type: "mrkdwn", Text: TruncateText(mrkdwn, slackSectionLimit)},
+	}
+}
+
+func slackImage(url string, altText string) SlackBlock {
+	return SlackBlock{
+		Type:     "image",
+		ImageUrl: url,
+		AltText:  altText,
+	}
+}
+
+func prepareSlackHook(webhook IHook, message SlackMessage) (PreparedHook, error) {
+	jsonBody, err := json.Marshal(message)
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	return PreparedHook{
+		WebhookId: webhook.GetId(),
+		Callback:  webhook.GetCallback(),
+		Body:      string(jsonBody),
+	}, nil
+}
+
+func buildSlackHookRss(rssHookBuild RssSend, webhook IHook) (PreparedHook, error) {
+	title := rssHookBuild.Item.Title
+	if rssHookBuild.Updated {
+		title = "[" + generateUpdatedLabelRss(rssHookBuild.Feed) + "] " + title
+	}
+
+	shortenedText, err := shortenAndRenderDescription(rssHookBuild.Item.Description, min(webhook.GetPreviewLength(), slackSectionLimit))
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	message := SlackMessage{
+		Text:   title,
+		Blocks: []SlackBlock{slackHeader(title)},
+	}
+
+	if description := strings.TrimSpace(shortenedText); description != "" {
+		message.Blocks = append(message.Blocks, slackSection(markdownToSlack(description)))
+	}
+
+	if optImage := findImageUrl(rssHookBuild.Item.Description); optImage != "" {
+		message.Blocks = append(message.Blocks, slackImage(optImage, title))
+	}
+
+	if rssHookBuild.Item.Link != "" {
+		message.Blocks = append(message.Blocks, SlackBlock{
+			Type: "context",
+			Elements: []SlackText{
+				{Type: "mrkdwn", Text: "<" + rssHookBuild.Item.Link + "|" + escapeSlack(generateUsernameRss(rssHookBuild.Feed)) + ">"},
+			},
+		})
+	}
+
+	return prepareSlackHook(webhook, message)
+}
+
+func buildSlackHookTwitter(twitterHook TwitterSend, webhook IHook) (PreparedHook, error) {
+	tweetText := TruncateText(twitterHook.Tweet.Text, min(webhook.GetPreviewLength(), slackSectionLimit))
+	message := SlackMessage{
+		Text: tweetText,
+		Blocks: []SlackBlock{
+			{
+				Type:     "context",
+				Elements: []SlackText{{Type: "mrkdwn", Text: "*@" + escapeSlack(twitterHook.Tweet.Author.Username) + "*"}},
+			},
+			slackSection(escapeSlack(tweetText)),
+		},
+	}
+
+	if len(twitterHook.Tweet.Attachments) > 0 {
+		message.Blocks = append(message.Blocks, slackImage(twitterHook.Tweet.Attachments[0], tweetText))
+	}
+
+	return prepareSlackHook(webhook, message)
+}
+
+func slackAlmanaxDay(almData dodugo.Almanax, date string) string {
+	almBonus := almData.GetBonus()
+	almBonusType := almBonus.GetType()
+	tribute := almData.GetTribute()
+	almItem := tribute.GetItem()
+
+	return fmt.Sprintf("*%s – %s*\n_%s_\n%s\n%dx *%s*", escapeSlack(date), escapeSlack(almBonusType.GetName()),
+		escapeSlack(almBonus.GetDescription()), formatKamas(almData.GetRewardKamas()), tribute.GetQuantity(), escapeSlack(almItem.GetName()))
+}
+
+func buildSlackHookAlmanax(almanaxSend AlmanaxSend, webhookIdx int) (PreparedHook, error) {
+	webhook := almanaxSend.Webhooks[webhookIdx]
+	intervalType := almanaxSend.IntervalType[webhookIdx]
+
+	var message SlackMessage
+	if intervalType == "daily" {
+		localAlmData, err := getLocalAlmData(almanaxSend.BuildInfo.almData, webhook.GetTimezone())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		almLocalDate, err := almanaxDate(almanaxSend, webhook, localAlmData.GetDate())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		almBonus := localAlmData.GetBonus()
+		almBonusType := almBonus.GetType()
+		tribute := localAlmData.GetTribute()
+		almItem := tribute.GetItem()
+		imageUrls := almItem.GetImageUrls()
+		imgBestResolution := imageUrls.GetIcon()
+		if imageUrls.HasSd() {
+			imgBestResolution = imageUrls.GetSd()
+		}
+
+		section := slackSection(fmt.Sprintf(":zap: *%s*\n_%s_\n\n:moneybag: %s\n\n:pray: %dx *%s*", escapeSlack(almBonusType.GetName()),
+			escapeSlack(almBonus.GetDescription()), formatKamas(localAlmData.GetRewardKamas()), tribute.GetQuantity(), escapeSlack(almItem.GetName())))
+		section.Accessory = &SlackImage{Type: "image", ImageUrl: imgBestResolution, AltText: almItem.GetName()}
+
+		message = SlackMessage{
+			Text:   almLocalDate + " – " + almBonusType.GetName(),
+			Blocks: []SlackBlock{slackHeader(almLocalDate), section},
+		}
+	} else {
+		localAlmData, err := buildAlmSpan(almanaxSend.TickTime, intervalType, webhook.GetTimezone(), almanaxSend.BuildInfo.almData)
+		if err != nil {
+			return PreparedHook{}, err
+		}
+		if len(localAlmData) == 0 {
+			return PreparedHook{}, fmt.Errorf("no almanax data for %s span", intervalType)
+		}
+
+		almLocalDateStart, err := almanaxDate(almanaxSend, webhook, localAlmData[0].GetDate())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+		almLocalDateEnd, err := almanaxDate(almanaxSend, webhook, localAlmData[len(localAlmData)-1].GetDate())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		intro := almanaxSpanIntro(almanaxSend.Feed.GetFeedName(), intervalType)
+		message = SlackMessage{
+			Text:   intro,
+			Blocks: []SlackBlock{slackHeader(almLocalDateStart + " - " + almLocalDateEnd), slackSection(escapeSlack(intro))},
+		}
+
+		// keeps the order of first appearance, unlike a map
+		var itemNames []string
+		itemsAgg := make(map[string]int32)
+		for _, almEntry := range localAlmData {
+			almLocalDate, err := almanaxDate(almanaxSend, webhook, almEntry.GetDate())
+			if err != nil {
+				return PreparedHook{}, err
+			}
+			message.Blocks = append(message.Blocks, slackSection(slackAlmanaxDay(almEntry, almLocalDate)))
+
+			tribute := almEntry.GetTribute()
+			almItem := tribute.GetItem()
+			if _, ok := itemsAgg[almItem.GetName()]; !ok {
+				itemNames = append(itemNames, almItem.GetName())
+			}
+			itemsAgg[almItem.GetName()] += tribute.GetQuantity()
+		}
+
+		totalItems := "*" + almanaxTotalLabel(almanaxSend.Feed.GetFeedName()) + "*\n"
+		for _, itemName := range itemNames {
+			totalItems += fmt.Sprintf("%dx *%s*\n", itemsAgg[itemName], escapeSlack(itemName))
+		}
+		message.Blocks = append(message.Blocks, SlackBlock{Type: "divider"}, slackSection(totalItems))
+	}
+
+	return prepareSlackHook(webhook, message)
+}
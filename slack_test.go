@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dofusdude/dodugo"
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAlmanaxSend builds an almanax send for a single webhook with data from yesterday until 40 days ahead.
+func testAlmanaxSend(webhook AlmanaxWebhook, intervalType string) AlmanaxSend {
+	almData := make(map[string]dodugo.Almanax)
+	for i := -1; i <= 40; i++ {
+		date := time.Now().UTC().Add(time.Duration(i) * 24 * time.Hour).Format("2006-01-02")
+
+		bonusType := dodugo.NewGetMetaAlmanaxBonuses200ResponseInner()
+		bonusType.SetId("bonus-" + date)
+		bonusType.SetName("Bonus <" + date + ">")
+		bonus := dodugo.NewAlmanaxBonus()
+		bonus.SetType(*bonusType)
+		bonus.SetDescription("More loot & xp")
+
+		images := dodugo.NewImages()
+		images.SetIcon("https://api.dofusdu.de/icon.png")
+		item := dodugo.NewAlmanaxTributeItem()
+		item.SetAnkamaId(42)
+		item.SetName("Wheat")
+		item.SetImageUrls(*images)
+		tribute := dodugo.NewAlmanaxTribute()
+		tribute.SetItem(*item)
+		tribute.SetQuantity(3)
+
+		entry := dodugo.NewAlmanax()
+		entry.SetDate(date)
+		entry.SetBonus(*bonus)
+		entry.SetTribute(*tribute)
+		entry.SetRewardKamas(12345)
+		almData[date] = *entry
+	}
+
+	return AlmanaxSend{
+		Feed:            AlmanaxFeed{HumanReadableId: "almanax_en", Language: "en"},
+		BuildInfo:       AlmanaxHookBuildInfo{almData: almData},
+		Webhooks:        []IHook{webhook},
+		OnlyPreMentions: []bool{false},
+		IntervalType:    []string{intervalType},
+		TickTime:        time.Now(),
+	}
+}
+
+func TestMarkdownToSlack(t *testing.T) {
+	assert.Equal(t, "*bold* and _italic_", markdownToSlack("**bold** and *italic*"))
+	assert.Equal(t, "<https://www.dofus.com|the devblog>", markdownToSlack("[the devblog](https://www.dofus.com)"))
+	assert.Equal(t, "*Patch notes*\nfixes", markdownToSlack("## Patch notes\nfixes"))
+	assert.Equal(t, "a &lt; b &amp;&amp; c", markdownToSlack("a < b && c"))
+}
+
+func TestIsSlackWebhookRejectsOtherHosts(t *testing.T) {
+	assert.False(t, isSlackWebhook("https://discord.com/api/webhooks/123/abc"))
+	assert.False(t, isSlackWebhook("http://hooks.slack.com/services/T/B/X"))
+}
+
+func TestBuildHookRssSlack(t *testing.T) {
+	webhook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://hooks.slack.com/services/T000/B000/XXXX",
+		Format:        SlackFormat,
+		PreviewLength: 2000,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title:       "Maintenance",
+			Link:        "https://www.dofus.com/fr/news/1",
+			Description: `<p>Servers are <b>down</b>.</p><img src="https://static.ankama.com/image.jpg">`,
+		},
+		Webhooks: []IHook{webhook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, webhook.Callback, hooks[0].Callback)
+
+	var message SlackMessage
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "Maintenance", message.Text)
+	assert.Len(t, message.Blocks, 4)
+	assert.Equal(t, "header", message.Blocks[0].Type)
+	assert.Equal(t, "Maintenance", message.Blocks[0].Text.Text)
+	assert.Equal(t, "section", message.Blocks[1].Type)
+	assert.Equal(t, "mrkdwn", message.Blocks[1].Text.Type)
+	assert.Equal(t, "Servers are *down*.", message.Blocks[1].Text.Text)
+	assert.Equal(t, "image", message.Blocks[2].Type)
+	assert.Equal(t, "https://static.ankama.com/image.jpg", message.Blocks[2].ImageUrl)
+	assert.Equal(t, "context", message.Blocks[3].Type)
+	assert.Equal(t, "<https://www.dofus.com/fr/news/1|Dofus3 News>", message.Blocks[3].Elements[0].Text)
+}
+
+func TestBuildHookAlmanaxSlack(t *testing.T) {
+	tz := "UTC"
+	webhook := AlmanaxWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://hooks.slack.com/services/T000/B000/XXXX",
+		Format:        SlackFormat,
+		WantsIsoDate:  true,
+		DailySettings: WebhookDailySettings{Timezone: &tz},
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	hooks, err := BuildHookAlmanax(testAlmanaxSend(webhook, "daily"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	var message SlackMessage
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Len(t, message.Blocks, 2)
+	assert.Equal(t, today, message.Blocks[0].Text.Text)
+	assert.Equal(t, fmt.Sprintf(":zap: *Bonus &lt;%s&gt;*\n_More loot &amp; xp_\n\n:moneybag: 12 345 K\n\n:pray: 3x *Wheat*", today), message.Blocks[1].Text.Text)
+	assert.Equal(t, "https://api.dofusdu.de/icon.png", message.Blocks[1].Accessory.ImageUrl)
+
+	hooks, err = BuildHookAlmanax(testAlmanaxSend(webhook, "weekly"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "Here are the bonuses for the week!", message.Text)
+	assert.Len(t, message.Blocks, 2+7+2)
+	assert.Equal(t, "*Total*\n21x *Wheat*\n", message.Blocks[len(message.Blocks)-1].Text.Text)
+
+	send := testAlmanaxSend(webhook, "daily")
+	send.OnlyPreMentions = []bool{true}
+	hooks, err = BuildHookAlmanax(send)
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 0)
+}
@@ -81,6 +81,13 @@ func getSocial(socialWebhookType string, parsedId uuid.UUID, repo Repository) (S
 		return SocialWebhookDTO{}, err
 	}
 
+	// hooks disabled after failing stay visible, so users can see why
+	if !found {
+		if found, err = repo.IsDisabledWebhook(socialWebhookType, parsedId); err != nil {
+			return SocialWebhookDTO{}, err
+		}
+	}
+
 	if !found {
 		return SocialWebhookDTO{}, errors.New("not found")
 	}
@@ -90,15 +97,23 @@ func getSocial(socialWebhookType string, parsedId uuid.UUID, repo Repository) (S
 		return SocialWebhookDTO{}, err
 	}
 
+	style := foundWebhook.GetDiscordStyle()
 	hookOut := SocialWebhookDTO{
-		Id:            foundWebhook.GetId(),
-		Blacklist:     foundWebhook.GetBlacklist(),
-		Whitelist:     foundWebhook.GetWhitelist(),
-		PreviewLength: foundWebhook.GetPreviewLength(),
-		Format:        foundWebhook.GetFormat(),
-		CreatedAt:     foundWebhook.GetCreatedAt(),
-		LastFiredAt:   foundWebhook.GetLastFiredAt(),
-		UpdatedAt:     foundWebhook.GetUpdatedAt(),
+		Id:             foundWebhook.GetId(),
+		Blacklist:      foundWebhook.GetBlacklist(),
+		Whitelist:      foundWebhook.GetWhitelist(),
+		PreviewLength:  foundWebhook.GetPreviewLength(),
+		Format:         foundWebhook.GetFormat(),
+		CreatedAt:      foundWebhook.GetCreatedAt(),
+		LastFiredAt:    foundWebhook.GetLastFiredAt(),
+		UpdatedAt:      foundWebhook.GetUpdatedAt(),
+		FailureCount:   foundWebhook.GetFailureCount(),
+		DisabledReason: foundWebhook.GetDisabledReason(),
+		DiscordThread:  foundWebhook.GetDiscordThread(),
+		Template:       foundWebhook.GetTemplate(),
+		Username:       style.Username,
+		AvatarUrl:      style.AvatarUrl,
+		EmbedColor:     style.EmbedColor,
 	}
 
 	var subbedFeeds []IFeed
@@ -120,12 +135,7 @@ func handleGetSocial(id string, socialWebhookType string, w http.ResponseWriter,
 		return
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Internal error.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var hookOut SocialWebhookDTO
 	hookOut, err = getSocial(socialWebhookType, parsedId, repo)
@@ -166,12 +176,7 @@ func handleDeleteSocial(socialWebhookType string, w http.ResponseWriter, r *http
 		return
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Internal error.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var found bool
 	if found, err = repo.HasSocialWebhook(socialWebhookType, parsedId); err != nil {
@@ -208,12 +213,7 @@ func handlePutSocial(socialWebhookType string, w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Could not connect to internal services.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var found bool
 	if found, err = repo.HasSocialWebhook(socialWebhookType, parsedId); err != nil {
@@ -226,12 +226,41 @@ func handlePutSocial(socialWebhookType string, w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	updateStyle := DiscordStyle{Username: updateSocialWebhook.Username, AvatarUrl: updateSocialWebhook.AvatarUrl, EmbedColor: updateSocialWebhook.EmbedColor}
+	if updateSocialWebhook.DiscordThread != nil || updateSocialWebhook.Template != nil || updateStyle != (DiscordStyle{}) {
+		var webhook ISocialHook
+		if webhook, err = repo.GetSocialHook(socialWebhookType, parsedId); err != nil {
+			http.Error(w, "Internal error.", http.StatusInternalServerError)
+			return
+		}
+
+		if msg := validateDiscordThread(socialWebhookType, webhook.GetFormat(), updateSocialWebhook.DiscordThread); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		if msg := validateDiscordTemplate(socialWebhookType, webhook.GetFormat(), updateSocialWebhook.Template); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		if msg := validateDiscordStyle(webhook.GetFormat(), updateStyle); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+	}
+
 	updateHook := SocialWebhookPutDb{
 		Id:            parsedId,
 		Blacklist:     updateSocialWebhook.Blacklist,
 		Whitelist:     updateSocialWebhook.Whitelist,
 		PreviewLength: updateSocialWebhook.PreviewLength,
 		Subscriptions: updateSocialWebhook.Subscriptions,
+		DiscordThread: updateSocialWebhook.DiscordThread,
+		Template:      updateSocialWebhook.Template,
+		Username:      updateSocialWebhook.Username,
+		AvatarUrl:     updateSocialWebhook.AvatarUrl,
+		EmbedColor:    updateSocialWebhook.EmbedColor,
 	}
 
 	if err = repo.UpdateSocialHook(socialWebhookType, updateHook); err != nil {
@@ -255,6 +284,14 @@ func handleCreateSocial(socialWebhookType string, w http.ResponseWriter, r *http
 		return
 	}
 
+	target := WebhookTarget{
+		Format:   newSocialWebhook.Format,
+		Callback: newSocialWebhook.Callback,
+		Telegram: newSocialWebhook.Telegram,
+		Matrix:   newSocialWebhook.Matrix,
+	}
+	target.Callback = targetCallback(target)
+	newSocialWebhook.Callback = target.Callback
 	if newSocialWebhook.Callback == "" {
 		http.Error(w, "Callback is required.", http.StatusBadRequest)
 		return
@@ -265,22 +302,26 @@ func handleCreateSocial(socialWebhookType string, w http.ResponseWriter, r *http
 		return
 	}
 
-	if newSocialWebhook.Format != "discord" {
-		http.Error(w, "Callback must have a known format.", http.StatusBadRequest)
+	if !validateTarget(w, r, target) {
 		return
 	}
 
-	if !isDiscordWebhook(newSocialWebhook.Callback) {
-		http.Error(w, "Callback is not a valid Discord URL.", http.StatusBadRequest)
+	if msg := validateDiscordThread(socialWebhookType, newSocialWebhook.Format, newSocialWebhook.DiscordThread); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
 
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Could not connect to internal services.", http.StatusInternalServerError)
+	if msg := validateDiscordTemplate(socialWebhookType, newSocialWebhook.Format, newSocialWebhook.Template); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if msg := validateDiscordStyle(newSocialWebhook.Format, DiscordStyle{Username: newSocialWebhook.Username, AvatarUrl: newSocialWebhook.AvatarUrl, EmbedColor: newSocialWebhook.EmbedColor}); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
-	defer repo.Deinit()
+
+	repo := requestRepository(r)
 
 	var hasCallback bool
 	switch socialWebhookType {
@@ -310,6 +351,11 @@ func handleCreateSocial(socialWebhookType string, w http.ResponseWriter, r *http
 		return
 	}
 
+	if newSocialWebhook.Secret, err = newTargetSecret(newSocialWebhook.Format); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
 	var id uuid.UUID
 	id, err = repo.CreateSocialHook(socialWebhookType, newSocialWebhook)
 	if err != nil {
@@ -334,6 +380,9 @@ func handleCreateSocial(socialWebhookType string, w http.ResponseWriter, r *http
 		}
 	}
 
+	// the secret is only shown once, on creation
+	hookOut.Secret = newSocialWebhook.Secret
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err = json.NewEncoder(w).Encode(hookOut); err != nil {
@@ -344,12 +393,7 @@ func handleCreateSocial(socialWebhookType string, w http.ResponseWriter, r *http
 
 func HandleGenGetMetaSubscriptions[T IFeed](w http.ResponseWriter, r *http.Request, getFeeds func([]uint64, Repository) ([]T, error)) {
 	var err error
-	var repo Repository
-	if err = repo.Init(r.Context()); err != nil {
-		http.Error(w, "Could not connect to internal services.", http.StatusInternalServerError)
-		return
-	}
-	defer repo.Deinit()
+	repo := requestRepository(r)
 
 	var feeds []T
 	if feeds, err = getFeeds([]uint64{}, repo); err != nil {
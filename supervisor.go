@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// almanaxStartDelay spreads the start of the almanax listeners, so they don't all hit the almanax API at once.
+const almanaxStartDelay = 2 * time.Second
+
+var feedSupervisor *FeedSupervisor
+
+type supervisedFeed struct {
+	feed   IFeed
+	cancel context.CancelFunc
+}
+
+// FeedSupervisor keeps one listener running for every feed in the database. Feeds that get added are started and
+// removed feeds are stopped on the next reconcile, without restarting the process.
+type FeedSupervisor struct {
+	ctx     context.Context
+	repo    Repository
+	listen  func(ctx context.Context, repo Repository, feed IFeed)
+	mu      sync.Mutex
+	running map[uint64]supervisedFeed
+	wg      sync.WaitGroup
+}
+
+// NewFeedSupervisor creates a supervisor whose listeners run until the given context is done.
+func NewFeedSupervisor(ctx context.Context, repo Repository) *FeedSupervisor {
+	return &FeedSupervisor{
+		ctx:     ctx,
+		repo:    repo,
+		listen:  listenFeed,
+		running: make(map[uint64]supervisedFeed),
+	}
+}
+
+func listenFeed(ctx context.Context, repo Repository, feed IFeed) {
+	switch f := feed.(type) {
+	case AlmanaxFeed:
+		ListenAlmanax(ctx, repo, f)
+	case TwitterFeed:
+		ListenTwitter(ctx, repo, f)
+	case RssFeed:
+		ListenRss(ctx, repo, f)
+	default:
+		log.Println("no listener for feed type", feed.GetType())
+	}
+}
+
+// Run reconciles the listeners every interval until the supervisor's context is done, then stops all of them.
+func (s *FeedSupervisor) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Reconcile(s.ctx); err != nil {
+				log.Println("Error while reconciling feeds ", err)
+			}
+		case <-s.ctx.Done():
+			ticker.Stop()
+			s.StopAll()
+			return
+		}
+	}
+}
+
+// Reconcile loads the feeds with the given context and brings the listeners in line with them.
+func (s *FeedSupervisor) Reconcile(ctx context.Context) error {
+	var err error
+	repo := s.repo.WithContext(ctx)
+
+	var feeds []IFeed
+	var almFeeds []AlmanaxFeed
+	if almFeeds, err = repo.GetAlmanaxFeeds([]uint64{}); err != nil {
+		return err
+	}
+	for _, feed := range almFeeds {
+		feeds = append(feeds, feed)
+	}
+
+	var twitterFeeds []TwitterFeed
+	if twitterFeeds, err = repo.GetTwitterFeeds([]uint64{}); err != nil {
+		return err
+	}
+	for _, feed := range twitterFeeds {
+		feeds = append(feeds, feed)
+	}
+
+	var rssFeeds []RssFeed
+	if rssFeeds, err = repo.GetRssFeeds([]uint64{}); err != nil {
+		return err
+	}
+	for _, feed := range rssFeeds {
+		feeds = append(feeds, feed)
+	}
+
+	s.reconcile(feeds)
+	return nil
+}
+
+// reconcile starts listeners for new feeds, stops the ones of feeds that are gone and restarts the ones of feeds
+// that changed.
+func (s *FeedSupervisor) reconcile(feeds []IFeed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[uint64]IFeed, len(feeds))
+	for _, feed := range feeds {
+		wanted[feed.GetId()] = feed
+	}
+
+	for id, running := range s.running {
+		if feed, ok := wanted[id]; !ok || !sameListener(feed, running.feed) {
+			running.cancel()
+			delete(s.running, id)
+			log.Println("stopped listener for feed", running.feed.GetFeedName())
+		}
+	}
+
+	almanaxStarts := 0
+	for _, feed := range feeds {
+		if _, ok := s.running[feed.GetId()]; ok {
+			continue
+		}
+
+		var delay time.Duration
+		if feed.GetType() == AlmanaxWebhookType {
+			delay = time.Duration(almanaxStarts) * almanaxStartDelay
+			almanaxStarts++
+		}
+
+		s.start(feed, delay)
+	}
+}
+
+// sameListener reports whether a listener of the running feed can keep serving the feed. Only the fields the
+// listeners read are compared, timestamps loaded from the database may differ in their location.
+func sameListener(feed IFeed, running IFeed) bool {
+	if feed.GetType() != running.GetType() || feed.GetFeedName() != running.GetFeedName() ||
+		feed.GetRSSUrl() != running.GetRSSUrl() || feed.GetTwitterId() != running.GetTwitterId() {
+		return false
+	}
+
+	almanaxFeed, isAlmanax := feed.(AlmanaxFeed)
+	runningAlmanaxFeed, runningIsAlmanax := running.(AlmanaxFeed)
+	if isAlmanax != runningIsAlmanax {
+		return false
+	}
+	return !isAlmanax || almanaxFeed.Language == runningAlmanaxFeed.Language
+}
+
+func (s *FeedSupervisor) start(feed IFeed, delay time.Duration) {
+	feedCtx, cancel := context.WithCancel(s.ctx)
+	s.running[feed.GetId()] = supervisedFeed{
+		feed:   feed,
+		cancel: cancel,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		select {
+		case <-time.After(delay):
+			s.listen(feedCtx, s.repo, feed)
+		case <-feedCtx.Done():
+		}
+	}()
+}
+
+func (s *FeedSupervisor) StopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, running := range s.running {
+		running.cancel()
+		delete(s.running, id)
+	}
+}
+
+// Wait blocks until all stopped listeners finished their current tick.
+func (s *FeedSupervisor) Wait() {
+	s.wg.Wait()
+}
+
+func (s *FeedSupervisor) Running() []IFeed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feeds := make([]IFeed, 0, len(s.running))
+	for _, running := range s.running {
+		feeds = append(feeds, running.feed)
+	}
+	return feeds
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeListeners struct {
+	mu      sync.Mutex
+	running map[uint64]IFeed
+	started int
+}
+
+func (l *fakeListeners) listen(ctx context.Context, _ Repository, feed IFeed) {
+	l.mu.Lock()
+	l.running[feed.GetId()] = feed
+	l.started++
+	l.mu.Unlock()
+
+	<-ctx.Done()
+
+	l.mu.Lock()
+	if l.running[feed.GetId()] == feed {
+		delete(l.running, feed.GetId())
+	}
+	l.mu.Unlock()
+}
+
+func (l *fakeListeners) snapshot() (map[uint64]IFeed, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	running := make(map[uint64]IFeed, len(l.running))
+	for id, feed := range l.running {
+		running[id] = feed
+	}
+	return running, l.started
+}
+
+func TestFeedSupervisorReconcile(t *testing.T) {
+	listeners := &fakeListeners{running: make(map[uint64]IFeed)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	supervisor := NewFeedSupervisor(ctx, Repository{})
+	supervisor.listen = listeners.listen
+
+	news := RssFeed{Id: 1, Url: "https://www.dofus.com/fr/rss/news.xml", ApiReadableId: "dofus2-fr-official-news"}
+	changelog := RssFeed{Id: 2, Url: "https://www.dofus.com/fr/rss/changelog.xml", ApiReadableId: "dofus2-fr-official-changelog"}
+	twitter := TwitterFeed{Id: 3, TwitterId: 42, HumanReadableId: "DOFUSfr"}
+
+	supervisor.reconcile([]IFeed{news, changelog, twitter})
+	assert.Eventually(t, func() bool {
+		running, _ := listeners.snapshot()
+		return len(running) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// changelog got removed, news got a new url and devblog is new
+	news.Url = "https://www.dofus.com/fr/rss/news2.xml"
+	devblog := RssFeed{Id: 4, Url: "https://www.dofus.com/fr/rss/devblog.xml", ApiReadableId: "dofus2-fr-official-devblog"}
+	supervisor.reconcile([]IFeed{news, twitter, devblog})
+
+	assert.Eventually(t, func() bool {
+		running, started := listeners.snapshot()
+		_, hasChangelog := running[2]
+		return len(running) == 3 && !hasChangelog && started == 5 && running[1] == IFeed(news)
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, supervisor.Running(), 3)
+
+	supervisor.StopAll()
+	assert.Eventually(t, func() bool {
+		running, _ := listeners.snapshot()
+		return len(running) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Len(t, supervisor.Running(), 0)
+}
+
+func TestFeedSupervisorKeepsUnchangedFeeds(t *testing.T) {
+	listeners := &fakeListeners{running: make(map[uint64]IFeed)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	supervisor := NewFeedSupervisor(ctx, Repository{})
+	supervisor.listen = listeners.listen
+	defer supervisor.StopAll()
+
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	news := RssFeed{Id: 1, Url: "https://www.dofus.com/fr/rss/news.xml", ApiReadableId: "dofus2-fr-official-news", CreatedAt: createdAt}
+	almanax := AlmanaxFeed{Id: 2, HumanReadableId: "dofus3-fr", Language: "fr", CreatedAt: createdAt}
+
+	supervisor.reconcile([]IFeed{news, almanax})
+	assert.Eventually(t, func() bool {
+		running, _ := listeners.snapshot()
+		return len(running) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// the same rows loaded again, with the timestamps in another location
+	reloadedNews := news
+	reloadedNews.CreatedAt = createdAt.In(time.FixedZone("CET", 3600))
+	reloadedAlmanax := almanax
+	reloadedAlmanax.CreatedAt = createdAt.Local()
+	supervisor.reconcile([]IFeed{reloadedNews, reloadedAlmanax})
+
+	time.Sleep(50 * time.Millisecond)
+	_, started := listeners.snapshot()
+	assert.Equal(t, 2, started)
+
+	// a new language needs a new listener
+	reloadedAlmanax.Language = "en"
+	supervisor.reconcile([]IFeed{reloadedNews, reloadedAlmanax})
+	assert.Eventually(t, func() bool {
+		running, started := listeners.snapshot()
+		return started == 3 && running[2].(AlmanaxFeed).Language == "en"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFeedSupervisorWaitsForListeners(t *testing.T) {
+	var finished atomic.Bool
+	started := make(chan struct{})
+	supervisor := NewFeedSupervisor(context.Background(), Repository{})
+	supervisor.listen = func(ctx context.Context, _ Repository, _ IFeed) {
+		close(started)
+		<-ctx.Done()
+		// still finishing a tick
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	}
+
+	supervisor.reconcile([]IFeed{RssFeed{Id: 1}})
+	<-started
+	supervisor.StopAll()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.True(t, waitTimeout(shutdownCtx, supervisor.Wait))
+	assert.True(t, finished.Load())
+}
+
+func TestWaitTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.False(t, waitTimeout(ctx, func() {
+		time.Sleep(time.Second)
+	}))
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dofusdude/dodugo"
+	"github.com/google/uuid"
+)
+
+const (
+	DiscordFormat  = "discord"
+	JsonFormat     = "json"
+	SlackFormat    = "slack"
+	TelegramFormat = "telegram"
+	MatrixFormat   = "matrix"
+)
+
+const (
+	SignatureHeader          = "X-Hooks-Signature"
+	SignatureTimestampHeader = "X-Hooks-Timestamp"
+)
+
+// isCustomFormat reports whether hooks of this format post somewhere else than Discord and need an API key.
+func isCustomFormat(format string) bool {
+	return format == JsonFormat
+}
+
+func isHttpsUrl(callback string) bool {
+	parsed, err := url.Parse(callback)
+	return err == nil && parsed.Scheme == "https" && parsed.Host != ""
+}
+
+// targetCallback returns the callback stored for a new webhook. Telegram and Matrix hooks have no URL, so they are
+// identified by their chat or room instead.
+func targetCallback(target WebhookTarget) string {
+	switch {
+	case target.Format == TelegramFormat && target.Telegram != nil:
+		return telegramCallback(*target.Telegram)
+	case target.Format == MatrixFormat && target.Matrix != nil:
+		return matrixCallback(*target.Matrix)
+	}
+	return target.Callback
+}
+
+// validateTarget checks the format and callback of a new webhook. Discord hooks are public, custom targets need an
+// API key with the targets:custom scope. It writes the error response itself if it returns false.
+func validateTarget(w http.ResponseWriter, r *http.Request, target WebhookTarget) bool {
+	if isCustomFormat(target.Format) {
+		key, ok := requestApiKey(r)
+		if !ok {
+			http.Error(w, "Custom targets need an API key.", http.StatusUnauthorized)
+			return false
+		}
+		if !key.HasScope(ScopeTargetsCustom) {
+			http.Error(w, "Missing scope "+ScopeTargetsCustom+".", http.StatusForbidden)
+			return false
+		}
+	}
+
+	switch target.Format {
+	case DiscordFormat:
+		if !isDiscordWebhook(target.Callback) {
+			http.Error(w, "Callback is not a valid Discord URL.", http.StatusBadRequest)
+			return false
+		}
+	case SlackFormat:
+		if !isSlackWebhook(target.Callback) {
+			http.Error(w, "Callback is not a valid Slack URL.", http.StatusBadRequest)
+			return false
+		}
+	case TelegramFormat:
+		if !isTelegramChat(target.Telegram) {
+			http.Error(w, "Telegram bot token or chat id is not valid.", http.StatusBadRequest)
+			return false
+		}
+	case MatrixFormat:
+		if !isMatrixRoom(target.Matrix) {
+			http.Error(w, "Matrix homeserver, room id or access token is not valid.", http.StatusBadRequest)
+			return false
+		}
+	case JsonFormat:
+		if !isHttpsUrl(target.Callback) {
+			http.Error(w, "Callback must be a https URL.", http.StatusBadRequest)
+			return false
+		}
+	default:
+		http.Error(w, "Callback must have a known format.", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// isValidTarget checks a stored callback again, for example before restoring its webhook.
+func isValidTarget(target WebhookTarget) bool {
+	switch target.Format {
+	case DiscordFormat:
+		return isDiscordWebhook(target.Callback)
+	case SlackFormat:
+		return isSlackWebhook(target.Callback)
+	case TelegramFormat:
+		return isTelegramChat(target.Telegram)
+	case MatrixFormat:
+		return isMatrixRoom(target.Matrix)
+	case JsonFormat:
+		return isHttpsUrl(target.Callback)
+	default:
+		return false
+	}
+}
+
+// markdownToHtml renders the markdown of the feed descriptions to the small HTML subset that chat APIs accept.
+func markdownToHtml(markdown string) string {
+	text := html.EscapeString(markdown)
+	text = slackHeadingRegex.ReplaceAllString(text, "<b>$1</b>")
+	text = slackLinkRegex.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = slackBoldRegex.ReplaceAllString(text, "<b>$2</b>")
+	return slackItalicRegex.ReplaceAllString(text, "<i>$1</i>")
+}
+
+// newTargetSecret returns the secret for a new webhook, if its format signs the requests.
+func newTargetSecret(format string) (*string, error) {
+	if format != JsonFormat {
+		return nil, nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	secret := "whsec_" + hex.EncodeToString(buf)
+	return &secret, nil
+}
+
+// signBody returns the signature headers for a body. The timestamp is part of the signed message, so receivers can
+// reject old requests that are replayed.
+func signBody(secret string, body string, timestamp time.Time) map[string]string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "." + body))
+
+	return map[string]string{
+		SignatureTimestampHeader: unix,
+		SignatureHeader:          "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+	}
+}
+
+func newJsonEvent(eventType string, feed IFeed) JsonEvent {
+	event := JsonEvent{
+		Id:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+	}
+	if feed != nil {
+		event.Feed = feed.GetFeedName()
+	}
+	return event
+}
+
+func prepareJsonHook(webhook IHook, event JsonEvent) (PreparedHook, error) {
+	jsonBody, err := json.Marshal(event)
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	return PreparedHook{
+		WebhookId: webhook.GetId(),
+		Callback:  webhook.GetCallback(),
+		Body:      string(jsonBody),
+		Secret:    webhook.GetSecret(),
+	}, nil
+}
+
+// newJsonEventItemRss describes the RSS item with the description shortened to the preview length of the webhook.
+func newJsonEventItemRss(rssHookBuild RssSend, webhook IHook) (*JsonEventItem, error) {
+	description, err := shortenAndRenderDescription(rssHookBuild.Item.Description, webhook.GetPreviewLength())
+	if err != nil {
+		return nil, err
+	}
+
+	item := &JsonEventItem{
+		Id:          rssItemKey(&rssHookBuild.Item),
+		Title:       rssHookBuild.Item.Title,
+		Url:         rssHookBuild.Item.Link,
+		Description: strings.TrimSpace(description),
+		PublishedAt: rssHookBuild.Item.PublishedParsed,
+		Updated:     rssHookBuild.Updated,
+	}
+
+	if len(rssHookBuild.Item.Authors) > 0 && rssHookBuild.Item.Authors[0] != nil {
+		item.Author = rssHookBuild.Item.Authors[0].Name
+	}
+
+	if image := findImageUrl(rssHookBuild.Item.Description); image != "" {
+		item.ImageUrl = &image
+	}
+
+	return item, nil
+}
+
+func buildJsonHookRss(rssHookBuild RssSend, webhook IHook) (PreparedHook, error) {
+	item, err := newJsonEventItemRss(rssHookBuild, webhook)
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	event := newJsonEvent(RSSWebhookType, rssHookBuild.Feed)
+	event.Item = item
+
+	return prepareJsonHook(webhook, event)
+}
+
+func buildJsonHookTwitter(twitterHook TwitterSend, webhook IHook) (PreparedHook, error) {
+	event := newJsonEvent(TwitterWebhookType, twitterHook.Feed)
+	event.Item = &JsonEventItem{
+		Author:      "@" + twitterHook.Tweet.Author.Username,
+		Description: TruncateText(twitterHook.Tweet.Text, webhook.GetPreviewLength()),
+		PublishedAt: &twitterHook.Tweet.CreatedAt,
+	}
+
+	if len(twitterHook.Tweet.Attachments) > 0 {
+		event.Item.ImageUrl = &twitterHook.Tweet.Attachments[0]
+	}
+
+	return prepareJsonHook(webhook, event)
+}
+
+func toJsonEventAlmanaxDay(almData dodugo.Almanax) JsonEventAlmanaxDay {
+	almBonus := almData.GetBonus()
+	almBonusType := almBonus.GetType()
+	tribute := almData.GetTribute()
+	almItem := tribute.GetItem()
+	imageUrls := almItem.GetImageUrls()
+
+	imageUrl := imageUrls.GetIcon()
+	if imageUrls.HasSd() {
+		imageUrl = imageUrls.GetSd()
+	}
+
+	return JsonEventAlmanaxDay{
+		Date: almData.GetDate(),
+		Bonus: JsonEventAlmanaxBonus{
+			Id:          almBonusType.GetId(),
+			Name:        almBonusType.GetName(),
+			Description: almBonus.GetDescription(),
+		},
+		Tribute: JsonEventAlmanaxTribute{
+			AnkamaId: almItem.GetAnkamaId(),
+			Name:     almItem.GetName(),
+			Quantity: tribute.GetQuantity(),
+			ImageUrl: imageUrl,
+		},
+		RewardKamas: almData.GetRewardKamas(),
+	}
+}
+
+// newJsonEventAlmanax describes the almanax days the webhook gets for its interval.
+func newJsonEventAlmanax(almanaxSend AlmanaxSend, webhookIdx int) (*JsonEventAlmanax, error) {
+	webhook := almanaxSend.Webhooks[webhookIdx]
+	intervalType := almanaxSend.IntervalType[webhookIdx]
+
+	almanax := &JsonEventAlmanax{
+		Interval: intervalType,
+	}
+
+	if intervalType == "daily" {
+		localAlmData, err := getLocalAlmData(almanaxSend.BuildInfo.almData, webhook.GetTimezone())
+		if err != nil {
+			return nil, err
+		}
+		almanax.Days = append(almanax.Days, toJsonEventAlmanaxDay(localAlmData))
+	} else {
+		localAlmData, err := buildAlmSpan(almanaxSend.TickTime, intervalType, webhook.GetTimezone(), almanaxSend.BuildInfo.almData)
+		if err != nil {
+			return nil, err
+		}
+		for _, almEntry := range localAlmData {
+			almanax.Days = append(almanax.Days, toJsonEventAlmanaxDay(almEntry))
+		}
+	}
+
+	return almanax, nil
+}
+
+func buildJsonHookAlmanax(almanaxSend AlmanaxSend, webhookIdx int) (PreparedHook, error) {
+	almanax, err := newJsonEventAlmanax(almanaxSend, webhookIdx)
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	event := newJsonEvent(AlmanaxWebhookType, almanaxSend.Feed)
+	event.Almanax = almanax
+
+	return prepareJsonHook(almanaxSend.Webhooks[webhookIdx], event)
+}
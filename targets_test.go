@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignBody(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	headers := signBody("whsec_test", `{"type":"rss"}`, timestamp)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte(`1700000000.{"type":"rss"}`))
+
+	assert.Equal(t, "1700000000", headers[SignatureTimestampHeader])
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), headers[SignatureHeader])
+	assert.NotEqual(t, headers[SignatureHeader], signBody("whsec_test", `{"type":"rss"}`, timestamp.Add(time.Second))[SignatureHeader])
+}
+
+func TestIsValidTarget(t *testing.T) {
+	assert.True(t, isValidTarget(WebhookTarget{Format: JsonFormat, Callback: "https://bots.example.com/hooks"}))
+	assert.False(t, isValidTarget(WebhookTarget{Format: JsonFormat, Callback: "http://bots.example.com/hooks"}))
+	assert.False(t, isValidTarget(WebhookTarget{Format: JsonFormat, Callback: "https://"}))
+	assert.False(t, isValidTarget(WebhookTarget{Format: "xml", Callback: "https://bots.example.com/hooks"}))
+}
+
+func TestBuildHookRssJson(t *testing.T) {
+	secret := "whsec_test"
+	jsonHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://bots.example.com/hooks",
+		Format:        JsonFormat,
+		Secret:        &secret,
+		PreviewLength: 2000,
+	}
+	discordHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/abc",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+	}
+
+	published := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title:           "Maintenance",
+			Link:            "https://www.dofus.com/fr/news/1",
+			GUID:            "news-1",
+			Description:     `<p>Servers are <b>down</b>.</p><img src="https://static.ankama.com/image.jpg">`,
+			PublishedParsed: &published,
+		},
+		Webhooks: []IHook{jsonHook, discordHook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 2)
+
+	assert.Equal(t, jsonHook.Id, hooks[0].WebhookId)
+	assert.Equal(t, secret, hooks[0].Secret)
+
+	var event JsonEvent
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &event))
+	assert.Equal(t, RSSWebhookType, event.Type)
+	assert.Equal(t, "dofus3-fr-official-news", event.Feed)
+	assert.NotEqual(t, uuid.Nil, event.Id)
+	assert.Nil(t, event.Almanax)
+	assert.Equal(t, "guid:news-1", event.Item.Id)
+	assert.Equal(t, "Maintenance", event.Item.Title)
+	assert.Equal(t, "https://www.dofus.com/fr/news/1", event.Item.Url)
+	assert.Equal(t, "Servers are **down**.", event.Item.Description)
+	assert.Equal(t, "https://static.ankama.com/image.jpg", *event.Item.ImageUrl)
+	assert.True(t, published.Equal(*event.Item.PublishedAt))
+	assert.False(t, event.Item.Updated)
+
+	assert.Equal(t, discordHook.Id, hooks[1].WebhookId)
+	assert.Equal(t, "", hooks[1].Secret)
+	var discordWebhook DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[1].Body), &discordWebhook))
+	assert.Equal(t, "Maintenance", *discordWebhook.Embeds[0].Title)
+}
+
+func TestDeliverSignsBody(t *testing.T) {
+	var signature, timestamp, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+		timestamp = r.Header.Get(SignatureTimestampHeader)
+		read, _ := io.ReadAll(r.Body)
+		body = string(read)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	res := NewWebhookClient(testRetryPolicy).Deliver(context.Background(), PreparedHook{
+		Callback: server.URL,
+		Body:     `{"type":"rss"}`,
+		Secret:   "whsec_test",
+	})
+	assert.True(t, res.Ok)
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), time.Unix(unix, 0), time.Minute)
+	assert.Equal(t, signBody("whsec_test", body, time.Unix(unix, 0))[SignatureHeader], signature)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	telegramTextLimit    = 4096
+	telegramCaptionLimit = 1024
+)
+
+// telegramApiUrl is a variable, so tests can point it to a fake Bot API.
+var telegramApiUrl = "https://api.telegram.org"
+
+var (
+	telegramBotTokenRegex    = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+	telegramBotTokenUrlRegex = regexp.MustCompile(`/bot[^/]+/`)
+)
+
+type telegramResponse struct {
+	Ok          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter      float64 `json:"retry_after"`
+		MigrateToChatId int64   `json:"migrate_to_chat_id"`
+	} `json:"parameters"`
+}
+
+func newTelegramTarget(botToken *string, chatId *string) *TelegramTarget {
+	if botToken == nil || chatId == nil {
+		return nil
+	}
+	return &TelegramTarget{BotToken: *botToken, ChatId: *chatId}
+}
+
+func telegramBotToken(target *TelegramTarget) *string {
+	if target == nil {
+		return nil
+	}
+	return &target.BotToken
+}
+
+func telegramChatId(target *TelegramTarget) *string {
+	if target == nil {
+		return nil
+	}
+	return &target.ChatId
+}
+
+// telegramCallback identifies a Telegram hook in place of a URL, so the callback uniqueness checks work the same for
+// all formats. It only contains the public bot id, not the token.
+func telegramCallback(target TelegramTarget) string {
+	botId, _, _ := strings.Cut(target.BotToken, ":")
+	return "telegram:" + botId + ":" + target.ChatId
+}
+
+func telegramMethodUrl(botToken string, method string) string {
+	return telegramApiUrl + "/bot" + botToken + "/" + method
+}
+
+// telegramMethodCallback is the callback stored for a Bot API call. It only contains the public bot id, the token is
+// put in by telegramDeliveryUrl right before sending, like the access token of Matrix.
+func telegramMethodCallback(botToken string, method string) string {
+	botId, _, _ := strings.Cut(botToken, ":")
+	return telegramMethodUrl(botId, method)
+}
+
+func telegramDeliveryUrl(callback string, botToken string) string {
+	botId, _, _ := strings.Cut(botToken, ":")
+	return strings.Replace(callback, "/bot"+botId+"/", "/bot"+botToken+"/", 1)
+}
+
+func isTelegramApiUrl(callback string) bool {
+	return strings.HasPrefix(callback, telegramApiUrl+"/bot")
+}
+
+// isTelegramChat checks the bot token and asks the Bot API whether the bot can see the chat.
+func isTelegramChat(target *TelegramTarget) bool {
+	if target == nil || !telegramBotTokenRegex.MatchString(target.BotToken) || target.ChatId == "" {
+		return false
+	}
+
+	request, err := http.NewRequest(http.MethodGet, telegramMethodUrl(target.BotToken, "getChat"), nil)
+	if err != nil {
+		return false
+	}
+	query := request.URL.Query()
+	query.Set("chat_id", target.ChatId)
+	request.URL.RawQuery = query.Encode()
+
+	client := http.Client{Timeout: 10 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return false
+	}
+	defer response.Body.Close()
+
+	var chat telegramResponse
+	if err = json.NewDecoder(response.Body).Decode(&chat); err != nil {
+		return false
+	}
+
+	return response.StatusCode == http.StatusOK && chat.Ok
+}
+
+// redactTelegramError removes the bot token from the URL in errors of the http client, before they get logged or
+// stored with the delivery attempts.
+func redactTelegramError(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+
+	redacted := *urlErr
+	redacted.URL = telegramBotTokenUrlRegex.ReplaceAllString(urlErr.URL, "/bot<redacted>/")
+	return &redacted
+}
+
+// telegramDeliveryError reads the error of a failed Bot API call. It is permanent when the chat is gone or the bot
+// lost access to it, so the hook can be disabled like a deleted Discord webhook.
+func telegramDeliveryError(statusCode int, body []byte) (bool, error) {
+	if statusCode >= 200 && statusCode < 300 {
+		return false, nil
+	}
+
+	var response telegramResponse
+	if err := json.Unmarshal(body, &response); err != nil || response.Description == "" {
+		return false, fmt.Errorf("telegram responded with status %d", statusCode)
+	}
+	err := errors.New("telegram: " + response.Description)
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		// invalid token, bot kicked from the group or blocked by the user
+		return true, err
+	case response.Parameters.MigrateToChatId != 0:
+		return true, fmt.Errorf("telegram: chat was upgraded to supergroup %d", response.Parameters.MigrateToChatId)
+	case statusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(response.Description), "chat not found"):
+		return true, err
+	}
+
+	return false, err
+}
+
+// fitTelegramText truncates the text to the limit Telegram counts after parsing the HTML.
+func fitTelegramText(header string, markdown string, limit int) string {
+	budget := limit - utf8.RuneCountInString(header) - 2
+	if budget <= 0 || markdown == "" {
+		return header
+	}
+	return header + "\n\n" + markdownToHtml(TruncateText(markdown, budget))
+}
+
+func prepareTelegramHook(webhook IHook, message TelegramMessage) (PreparedHook, error) {
+	target := webhook.GetTelegramTarget()
+	if target == nil {
+		return PreparedHook{}, fmt.Errorf("telegram webhook %s has no bot token or chat id", webhook.GetId())
+	}
+
+	method := "sendMessage"
+	if message.Photo != "" {
+		method = "sendPhoto"
+	}
+
+	message.ChatId = target.ChatId
+	message.ParseMode = "HTML"
+	jsonBody, err := json.Marshal(message)
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	return PreparedHook{
+		WebhookId:   webhook.GetId(),
+		Callback:    telegramMethodCallback(target.BotToken, method),
+		Body:        string(jsonBody),
+		AccessToken: target.BotToken,
+	}, nil
+}
+
+func buildTelegramHookRss(rssHookBuild RssSend, webhook IHook) (PreparedHook, error) {
+	title := rssHookBuild.Item.Title
+	if rssHookBuild.Updated {
+		title = "[" + generateUpdatedLabelRss(rssHookBuild.Feed) + "] " + title
+	}
+
+	header := "<b>" + html.EscapeString(title) + "</b>"
+	if rssHookBuild.Item.Link != "" {
+		header = `<b><a href="` + html.EscapeString(rssHookBuild.Item.Link) + `">` + html.EscapeString(title) + "</a></b>"
+	}
+
+	var message TelegramMessage
+	limit := telegramTextLimit
+	if optImage := findImageUrl(rssHookBuild.Item.Description); optImage != "" {
+		message.Photo = optImage
+		limit = telegramCaptionLimit
+	}
+
+	markdown, err := shortenAndRenderDescription(rssHookBuild.Item.Description, webhook.GetPreviewLength())
+	if err != nil {
+		return PreparedHook{}, err
+	}
+
+	text := fitTelegramText(header, strings.TrimSpace(markdown), limit)
+	if message.Photo != "" {
+		message.Caption = text
+	} else {
+		message.Text = text
+	}
+
+	return prepareTelegramHook(webhook, message)
+}
+
+func buildTelegramHookTwitter(twitterHook TwitterSend, webhook IHook) (PreparedHook, error) {
+	var message TelegramMessage
+	limit := telegramTextLimit
+	if len(twitterHook.Tweet.Attachments) > 0 {
+		message.Photo = twitterHook.Tweet.Attachments[0]
+		limit = telegramCaptionLimit
+	}
+
+	header := "<b>@" + html.EscapeString(twitterHook.Tweet.Author.Username) + "</b>"
+	tweetText := TruncateText(twitterHook.Tweet.Text, min(webhook.GetPreviewLength(), limit-utf8.RuneCountInString(header)-2))
+	text := header + "\n\n" + html.EscapeString(tweetText)
+	if message.Photo != "" {
+		message.Caption = text
+	} else {
+		message.Text = text
+	}
+
+	return prepareTelegramHook(webhook, message)
+}
+
+func buildTelegramHookAlmanax(almanaxSend AlmanaxSend, webhookIdx int) (PreparedHook, error) {
+	webhook := almanaxSend.Webhooks[webhookIdx]
+	intervalType := almanaxSend.IntervalType[webhookIdx]
+
+	var message TelegramMessage
+	if intervalType == "daily" {
+		localAlmData, err := getLocalAlmData(almanaxSend.BuildInfo.almData, webhook.GetTimezone())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		almLocalDate, err := almanaxDate(almanaxSend, webhook, localAlmData.GetDate())
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		almBonus := localAlmData.GetBonus()
+		almBonusType := almBonus.GetType()
+		tribute := localAlmData.GetTribute()
+		almItem := tribute.GetItem()
+		imageUrls := almItem.GetImageUrls()
+		message.Photo = imageUrls.GetIcon()
+		if imageUrls.HasSd() {
+			message.Photo = imageUrls.GetSd()
+		}
+
+		message.Caption = fmt.Sprintf("<b>%s</b>\n\n⚡ <b>%s</b>\n<i>%s</i>\n\n💰 %s\n\n🙏 %dx <b>%s</b>", html.EscapeString(almLocalDate),
+			html.EscapeString(almBonusType.GetName()), html.EscapeString(almBonus.GetDescription()), formatKamas(localAlmData.GetRewardKamas()),
+			tribute.GetQuantity(), html.EscapeString(almItem.GetName()))
+	} else {
+		localAlmData, err := buildAlmSpan(almanaxSend.TickTime, intervalType, webhook.GetTimezone(), almanaxSend.BuildInfo.almData)
+		if err != nil {
+			return PreparedHook{}, err
+		}
+
+		lines := []string{"<b>" + html.EscapeString(almanaxSpanIntro(almanaxSend.Feed.GetFeedName(), intervalType)) + "</b>"}
+		var itemNames []string
+		itemsAgg := make(map[string]int32)
+		for _, almEntry := range localAlmData {
+			almLocalDate, err := almanaxDate(almanaxSend, webhook, almEntry.GetDate())
+			if err != nil {
+				return PreparedHook{}, err
+			}
+
+			almBonus := almEntry.GetBonus()
+			almBonusType := almBonus.GetType()
+			tribute := almEntry.GetTribute()
+			almItem := tribute.GetItem()
+			// the descriptions are left out, a month of them does not fit into one message
+			lines = append(lines, fmt.Sprintf("<b>%s – %s</b>\n%s · %dx %s", html.EscapeString(almLocalDate), html.EscapeString(almBonusType.GetName()),
+				formatKamas(almEntry.GetRewardKamas()), tribute.GetQuantity(), html.EscapeString(almItem.GetName())))
+
+			if _, ok := itemsAgg[almItem.GetName()]; !ok {
+				itemNames = append(itemNames, almItem.GetName())
+			}
+			itemsAgg[almItem.GetName()] += tribute.GetQuantity()
+		}
+
+		total := "<b>" + almanaxTotalLabel(almanaxSend.Feed.GetFeedName()) + "</b>"
+		for _, itemName := range itemNames {
+			total += fmt.Sprintf("\n%dx %s", itemsAgg[itemName], html.EscapeString(itemName))
+		}
+		lines = append(lines, total)
+
+		message.Text = strings.Join(lines, "\n\n")
+	}
+
+	return prepareTelegramHook(webhook, message)
+}
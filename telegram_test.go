@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTelegramTarget() (*string, *string) {
+	botToken := "123456:ABC-def_ghi"
+	chatId := "-100987"
+	return &botToken, &chatId
+}
+
+func TestTelegramCallback(t *testing.T) {
+	assert.Equal(t, "telegram:123456:-100987", telegramCallback(TelegramTarget{BotToken: "123456:ABC-def_ghi", ChatId: "-100987"}))
+	assert.Equal(t, "telegram:123456:-100987", targetCallback(WebhookTarget{Format: TelegramFormat, Telegram: &TelegramTarget{BotToken: "123456:ABC-def_ghi", ChatId: "-100987"}}))
+	assert.Equal(t, "https://bots.example.com", targetCallback(WebhookTarget{Format: JsonFormat, Callback: "https://bots.example.com", Telegram: &TelegramTarget{BotToken: "1:a", ChatId: "2"}}))
+	assert.Equal(t, "", targetCallback(WebhookTarget{Format: TelegramFormat}))
+}
+
+func TestMarkdownToHtml(t *testing.T) {
+	assert.Equal(t, "<b>bold</b> and <i>italic</i>", markdownToHtml("**bold** and *italic*"))
+	assert.Equal(t, `<a href="https://www.dofus.com/?a=1&amp;b=2">the devblog</a>`, markdownToHtml("[the devblog](https://www.dofus.com/?a=1&b=2)"))
+	assert.Equal(t, "<b>Patch notes</b>\nfixes", markdownToHtml("## Patch notes\nfixes"))
+	assert.Equal(t, "a &lt; b &amp;&amp; c", markdownToHtml("a < b && c"))
+}
+
+func TestTelegramDeliveryError(t *testing.T) {
+	permanent, err := telegramDeliveryError(http.StatusBadRequest, []byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	assert.True(t, permanent)
+	assert.EqualError(t, err, "telegram: Bad Request: chat not found")
+
+	permanent, err = telegramDeliveryError(http.StatusForbidden, []byte(`{"ok":false,"error_code":403,"description":"Forbidden: bot was kicked from the group chat"}`))
+	assert.True(t, permanent)
+	assert.EqualError(t, err, "telegram: Forbidden: bot was kicked from the group chat")
+
+	permanent, err = telegramDeliveryError(http.StatusBadRequest, []byte(`{"ok":false,"error_code":400,"description":"Bad Request: group chat was upgraded to a supergroup chat","parameters":{"migrate_to_chat_id":-100123}}`))
+	assert.True(t, permanent)
+	assert.EqualError(t, err, "telegram: chat was upgraded to supergroup -100123")
+
+	permanent, err = telegramDeliveryError(http.StatusBadRequest, []byte(`{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier/HTTP URL specified"}`))
+	assert.False(t, permanent)
+	assert.NotNil(t, err)
+
+	permanent, err = telegramDeliveryError(http.StatusBadGateway, []byte("<html>bad gateway</html>"))
+	assert.False(t, permanent)
+	assert.EqualError(t, err, "telegram responded with status 502")
+}
+
+func TestBuildHookRssTelegram(t *testing.T) {
+	botToken, chatId := testTelegramTarget()
+	webhook := RssWebhook{
+		Id:               uuid.New(),
+		Callback:         "telegram:123456:-100987",
+		Format:           TelegramFormat,
+		PreviewLength:    2000,
+		TelegramBotToken: botToken,
+		TelegramChatId:   chatId,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title:       "Maintenance & more",
+			Link:        "https://www.dofus.com/fr/news/1",
+			Description: `<p>Servers are <b>down</b>.</p><img src="https://static.ankama.com/image.jpg">`,
+		},
+		Webhooks: []IHook{webhook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, telegramApiUrl+"/bot123456/sendPhoto", hooks[0].Callback)
+	assert.Equal(t, *botToken, hooks[0].AccessToken)
+
+	var message TelegramMessage
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "-100987", message.ChatId)
+	assert.Equal(t, "HTML", message.ParseMode)
+	assert.Equal(t, "https://static.ankama.com/image.jpg", message.Photo)
+	assert.Equal(t, `<b><a href="https://www.dofus.com/fr/news/1">Maintenance &amp; more</a></b>`+"\n\nServers are <b>down</b>.", message.Caption)
+	assert.Empty(t, message.Text)
+}
+
+func TestBuildHookAlmanaxTelegram(t *testing.T) {
+	tz := "UTC"
+	botToken, chatId := testTelegramTarget()
+	webhook := AlmanaxWebhook{
+		Id:               uuid.New(),
+		Callback:         "telegram:123456:-100987",
+		Format:           TelegramFormat,
+		WantsIsoDate:     true,
+		DailySettings:    WebhookDailySettings{Timezone: &tz},
+		TelegramBotToken: botToken,
+		TelegramChatId:   chatId,
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	hooks, err := BuildHookAlmanax(testAlmanaxSend(webhook, "daily"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, telegramApiUrl+"/bot123456/sendPhoto", hooks[0].Callback)
+	assert.Equal(t, *botToken, hooks[0].AccessToken)
+
+	var message TelegramMessage
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "https://api.dofusdu.de/icon.png", message.Photo)
+	assert.Equal(t, fmt.Sprintf("<b>%s</b>\n\n⚡ <b>Bonus &lt;%s&gt;</b>\n<i>More loot &amp; xp</i>\n\n💰 12 345 K\n\n🙏 3x <b>Wheat</b>", today, today), message.Caption)
+
+	hooks, err = BuildHookAlmanax(testAlmanaxSend(webhook, "weekly"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+	assert.Equal(t, telegramApiUrl+"/bot123456/sendMessage", hooks[0].Callback)
+
+	message = TelegramMessage{}
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Contains(t, message.Text, "<b>Here are the bonuses for the week!</b>")
+	tomorrow := time.Now().UTC().Add(24 * time.Hour).Format("2006-01-02")
+	assert.Contains(t, message.Text, fmt.Sprintf("<b>%s – Bonus &lt;%s&gt;</b>\n12 345 K · 3x Wheat", tomorrow, tomorrow))
+	assert.Contains(t, message.Text, "<b>Total</b>\n21x Wheat")
+}
+
+func TestDeliverTelegram(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/bot123456:ABC-def_ghi/getChat":
+			if r.URL.Query().Get("chat_id") != "-100987" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":-100987,"type":"channel"}}`))
+		case "/bot123456:ABC-def_ghi/sendMessage":
+			read, _ := io.ReadAll(r.Body)
+			body = string(read)
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	defaultApiUrl := telegramApiUrl
+	telegramApiUrl = server.URL
+	defer func() { telegramApiUrl = defaultApiUrl }()
+
+	assert.True(t, isTelegramChat(&TelegramTarget{BotToken: "123456:ABC-def_ghi", ChatId: "-100987"}))
+	assert.False(t, isTelegramChat(&TelegramTarget{BotToken: "123456:ABC-def_ghi", ChatId: "-1"}))
+	assert.False(t, isTelegramChat(&TelegramTarget{BotToken: "not a token", ChatId: "-100987"}))
+
+	client := NewWebhookClient(testRetryPolicy)
+	res := client.Deliver(context.Background(), PreparedHook{
+		Callback:    telegramMethodCallback("123456:ABC-def_ghi", "sendMessage"),
+		Body:        `{"chat_id":"-100987","text":"hi","parse_mode":"HTML"}`,
+		AccessToken: "123456:ABC-def_ghi",
+	})
+	assert.True(t, res.Ok)
+	assert.Equal(t, `{"chat_id":"-100987","text":"hi","parse_mode":"HTML"}`, body)
+
+	res = client.Deliver(context.Background(), PreparedHook{
+		Callback:    telegramMethodCallback("654321:ABC-def_ghi", "sendMessage"),
+		Body:        `{"chat_id":"-1","text":"hi","parse_mode":"HTML"}`,
+		AccessToken: "654321:ABC-def_ghi",
+	})
+	assert.False(t, res.Ok)
+	assert.True(t, res.Permanent)
+	assert.Equal(t, 1, res.Attempts)
+	assert.EqualError(t, res.Err, "telegram: Bad Request: chat not found")
+}
+
+func TestTelegramMethodCallback(t *testing.T) {
+	callback := telegramMethodCallback("123456:ABC-def_ghi", "sendMessage")
+	assert.NotContains(t, callback, "ABC-def_ghi")
+	assert.True(t, isTelegramApiUrl(callback))
+
+	hook := PreparedHook{Callback: callback, AccessToken: "123456:ABC-def_ghi"}
+	assert.Equal(t, telegramApiUrl+"/bot123456:ABC-def_ghi/sendMessage", deliveryUrl(hook))
+	assert.Equal(t, "", deliveryAuthorization(hook))
+	assert.Equal(t, "Bearer syt_test", deliveryAuthorization(PreparedHook{Callback: "https://matrix.org/_matrix/client/v3/rooms/!a:b/send/m.room.message/1", AccessToken: "syt_test"}))
+}
+
+func TestRedactTelegramError(t *testing.T) {
+	_, err := http.Get("http://127.0.0.1:0/bot123456:ABC-def_ghi/sendMessage")
+	assert.NotNil(t, err)
+	assert.NotContains(t, redactTelegramError(err).Error(), "ABC-def_ghi")
+	assert.Contains(t, redactTelegramError(err).Error(), "/bot<redacted>/sendMessage")
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+)
+
+const (
+	// discordTemplateMaxLength is the maximum length of a template source.
+	discordTemplateMaxLength = 4000
+	// discordTemplateMaxOutput stops templates from rendering payloads Discord would refuse anyway.
+	discordTemplateMaxOutput = 16000
+	// discordTemplateMaxNodes and discordTemplateMaxDepth bound the size of the parse tree.
+	discordTemplateMaxNodes = 1000
+	discordTemplateMaxDepth = 16
+	// discordTemplateMaxRangeDepth allows ranges over the days of a range, but not deeper.
+	discordTemplateMaxRangeDepth = 2
+)
+
+// discordTemplateTimeout is how long a template may render before it is given up.
+var discordTemplateTimeout = 250 * time.Millisecond
+
+var (
+	errTemplateOutputTooLong = errors.New("rendered message is too long")
+	errTemplateTimeout       = errors.New("rendering took too long")
+)
+
+// limitedBuffer fails the template execution once the rendered output grows past its limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputTooLong
+	}
+	return b.Buffer.Write(p)
+}
+
+// templateDate reads a date given to localDate as ISO date, empty for missing dates.
+func templateDate(value any) (string, error) {
+	switch date := value.(type) {
+	case string:
+		if date == "" {
+			return "", nil
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return "", fmt.Errorf("localDate needs a date like 2006-01-02, got %q", date)
+		}
+		return date, nil
+	case time.Time:
+		return date.Format("2006-01-02"), nil
+	case *time.Time:
+		if date == nil {
+			return "", nil
+		}
+		return date.Format("2006-01-02"), nil
+	default:
+		return "", fmt.Errorf("localDate needs a date, got %T", value)
+	}
+}
+
+// discordTemplateFuncs are the only functions templates can call besides the text/template builtins. formatDate
+// writes an ISO date the way the webhook wants it.
+func discordTemplateFuncs(formatDate func(date string) (string, error)) template.FuncMap {
+	return template.FuncMap{
+		"truncate": func(length int, text string) string {
+			return TruncateText(text, length)
+		},
+		"markdown": func(html string) (string, error) {
+			markdown, err := md.NewConverter("", true, nil).ConvertString(html)
+			if err != nil {
+				return "", err
+			}
+			return filterMarkdownImageStrings(markdown), nil
+		},
+		"formatKamas": formatKamas,
+		"localDate": func(value any) (string, error) {
+			date, err := templateDate(value)
+			if err != nil || date == "" {
+				return "", err
+			}
+			return formatDate(date)
+		},
+		"json": func(value any) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+	}
+}
+
+func parseDiscordTemplate(source string, formatDate func(date string) (string, error)) (*template.Template, error) {
+	tmpl, err := template.New("discord").Option("missingkey=error").Funcs(discordTemplateFuncs(formatDate)).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// define and block would allow recursive templates
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("define and block are not supported")
+	}
+
+	if tmpl.Tree == nil {
+		return tmpl, nil
+	}
+
+	nodes := 0
+	if err = checkTemplateNode(tmpl.Tree.Root, 0, 0, &nodes); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// isTemplateDataPath reports whether the pipeline is only a field of the data, like .Almanax.Days or $day.Bonus.
+// Ranges over anything else could loop over a number of any size.
+func isTemplateDataPath(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return true
+	case *parse.VariableNode:
+		return len(arg.Ident) > 1
+	default:
+		return false
+	}
+}
+
+// checkTemplateNode walks the parse tree and rejects templates that are too big, too deeply nested or could loop for
+// a long time without writing anything.
+func checkTemplateNode(node parse.Node, depth int, rangeDepth int, nodes *int) error {
+	if node == nil {
+		return nil
+	}
+
+	*nodes++
+	if *nodes > discordTemplateMaxNodes {
+		return fmt.Errorf("template must not have more than %d actions", discordTemplateMaxNodes)
+	}
+	if depth > discordTemplateMaxDepth {
+		return fmt.Errorf("template must not be nested deeper than %d", discordTemplateMaxDepth)
+	}
+
+	var branch *parse.BranchNode
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child, depth, rangeDepth, nodes); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.ActionNode:
+		return checkTemplateNode(n.Pipe, depth+1, rangeDepth, nodes)
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			if err := checkTemplateNode(cmd, depth+1, rangeDepth, nodes); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkTemplateNode(arg, depth+1, rangeDepth, nodes); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.ChainNode:
+		return checkTemplateNode(n.Node, depth+1, rangeDepth, nodes)
+	case *parse.BreakNode, *parse.ContinueNode:
+		return nil
+	case *parse.TemplateNode:
+		return errors.New("template calls are not supported")
+	case *parse.IfNode:
+		branch = &n.BranchNode
+	case *parse.WithNode:
+		branch = &n.BranchNode
+	case *parse.RangeNode:
+		if !isTemplateDataPath(n.Pipe) {
+			return errors.New("range only works over fields like .Almanax.Days")
+		}
+		rangeDepth++
+		if rangeDepth > discordTemplateMaxRangeDepth {
+			return fmt.Errorf("ranges must not be nested deeper than %d", discordTemplateMaxRangeDepth)
+		}
+		branch = &n.BranchNode
+	default:
+		return nil
+	}
+
+	if err := checkTemplateNode(branch.Pipe, depth+1, rangeDepth, nodes); err != nil {
+		return err
+	}
+	if err := checkTemplateNode(branch.List, depth+1, rangeDepth, nodes); err != nil {
+		return err
+	}
+	if branch.ElseList == nil {
+		return nil
+	}
+	return checkTemplateNode(branch.ElseList, depth+1, rangeDepth, nodes)
+}
+
+// executeDiscordTemplate stops waiting for templates that render too long. The checks of the parse tree should prevent
+// that, this is the last line of defense.
+func executeDiscordTemplate(tmpl *template.Template, out *limitedBuffer, data DiscordTemplateData) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.Execute(out, data)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(discordTemplateTimeout):
+		return errTemplateTimeout
+	}
+}
+
+// renderDiscordTemplate renders the template to a Discord message. Username and avatar are taken from defaults
+// unless the template sets them.
+func renderDiscordTemplate(source string, formatDate func(date string) (string, error), defaults DiscordWebhook, data DiscordTemplateData) (DiscordWebhook, error) {
+	tmpl, err := parseDiscordTemplate(source, formatDate)
+	if err != nil {
+		return DiscordWebhook{}, err
+	}
+
+	out := &limitedBuffer{limit: discordTemplateMaxOutput}
+	if err = executeDiscordTemplate(tmpl, out, data); err != nil {
+		return DiscordWebhook{}, err
+	}
+
+	discordWebhook := DiscordWebhook{
+		Username:  defaults.Username,
+		AvatarUrl: defaults.AvatarUrl,
+	}
+	decoder := json.NewDecoder(&out.Buffer)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&discordWebhook); err != nil {
+		return DiscordWebhook{}, fmt.Errorf("rendered message is not a Discord message: %w", err)
+	}
+
+	if (discordWebhook.Content == nil || strings.TrimSpace(*discordWebhook.Content) == "") && len(discordWebhook.Embeds) == 0 {
+		return DiscordWebhook{}, errors.New("rendered message has neither content nor embeds")
+	}
+
+	return discordWebhook, nil
+}
+
+// rssTemplateDate writes dates of RSS items in the language of the feed.
+func rssTemplateDate(feed IFeed) func(date string) (string, error) {
+	return func(date string) (string, error) {
+		layout := localDateLayout(rssFeedLanguage(feed))
+		if layout == "" {
+			return date, nil
+		}
+
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return "", err
+		}
+		return parsed.Format(layout), nil
+	}
+}
+
+// almanaxTemplateDate writes almanax dates like the built-in messages do.
+func almanaxTemplateDate(almanaxSend AlmanaxSend, webhook IHook) func(date string) (string, error) {
+	return func(date string) (string, error) {
+		return almanaxDate(almanaxSend, webhook, date)
+	}
+}
+
+func sampleTemplateDays(count int) []JsonEventAlmanaxDay {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := make([]JsonEventAlmanaxDay, 0, count)
+	for i := 0; i < count; i++ {
+		days = append(days, JsonEventAlmanaxDay{
+			Date: start.AddDate(0, 0, i).Format("2006-01-02"),
+			Bonus: JsonEventAlmanaxBonus{
+				Id:          "plentiful-harvest",
+				Name:        "Plentiful Harvest",
+				Description: "Harvesting gives more resources.",
+			},
+			Tribute: JsonEventAlmanaxTribute{
+				AnkamaId: 289,
+				Name:     "Wheat",
+				Quantity: 12,
+				ImageUrl: "https://api.dofusdu.de/dofus3/v1/img/item/289-400.png",
+			},
+			RewardKamas: 12345,
+		})
+	}
+	return days
+}
+
+// sampleTemplateData is what templates are tried with before they are saved, one entry per kind of message.
+func sampleTemplateData(webhookType string) []DiscordTemplateData {
+	if webhookType == AlmanaxWebhookType {
+		return []DiscordTemplateData{
+			{
+				Feed:     "almanax_en",
+				Almanax:  &JsonEventAlmanax{Interval: "daily", Days: sampleTemplateDays(1)},
+				Mentions: "<@&123456789012345678>",
+				Previews: []DiscordEmbedField{{Name: "Plentiful Harvest tomorrow!", Value: "<@123456789012345678>\nHarvesting gives more resources."}},
+			},
+			{
+				Feed:    "almanax_en",
+				Almanax: &JsonEventAlmanax{Interval: "weekly", Days: sampleTemplateDays(7)},
+			},
+		}
+	}
+
+	imageUrl := "https://static.ankama.com/dofus/news/sample.jpg"
+	publishedAt := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	return []DiscordTemplateData{
+		{
+			Feed: "dofus3-en-official-news",
+			Item: &JsonEventItem{
+				Id:          "https://www.dofus.com/en/news/sample",
+				Title:       "Sample \"news\" title",
+				Url:         "https://www.dofus.com/en/news/sample",
+				Author:      "Ankama",
+				Description: "A short *preview* of the news.",
+				ImageUrl:    &imageUrl,
+				PublishedAt: &publishedAt,
+			},
+		},
+	}
+}
+
+// validateDiscordTemplate checks the template of a webhook by rendering it with sample data and returns the error
+// message if it is not valid.
+func validateDiscordTemplate(webhookType string, format string, source *string) string {
+	if isEmptyText(source) {
+		return ""
+	}
+
+	if format != DiscordFormat {
+		return "Templates are only supported for Discord webhooks."
+	}
+
+	if webhookType != RSSWebhookType && webhookType != AlmanaxWebhookType {
+		return "Templates are only supported for RSS and almanax webhooks."
+	}
+
+	if len(*source) > discordTemplateMaxLength {
+		return fmt.Sprintf("Template must not be longer than %d characters.", discordTemplateMaxLength)
+	}
+
+	sampleDate := func(date string) (string, error) {
+		return date, nil
+	}
+	if _, err := parseDiscordTemplate(*source, sampleDate); err != nil {
+		return "Template is not valid: " + err.Error()
+	}
+
+	for _, data := range sampleTemplateData(webhookType) {
+		if _, err := renderDiscordTemplate(*source, sampleDate, DiscordWebhook{}, data); err != nil {
+			return "Template does not render: " + err.Error()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRssTemplate = `{"content": {{json (printf "New: %s" (truncate 12 .Item.Title))}}, "username": "News",
+"embeds": [{"title": {{json .Item.Title}}, "url": {{json .Item.Url}}, "color": 16711680, "description": {{json (printf "%s (%s)" .Item.Description (localDate .Item.PublishedAt))}}}]}`
+
+const testAlmanaxTemplate = `{"content": {{json .Mentions}}, "embeds": [{"title": {{json .Almanax.Interval}}, "fields": [
+{{- range $i, $day := .Almanax.Days}}{{if $i}},{{end}}{"name": {{json (localDate $day.Date)}}, "value": {{json (printf "%s %s %dx %s" $day.Bonus.Name (formatKamas $day.RewardKamas) $day.Tribute.Quantity (markdown $day.Bonus.Description))}}}{{end -}}
+]}]}`
+
+func TestValidateDiscordTemplate(t *testing.T) {
+	rssTemplate := testRssTemplate
+	almanaxTemplate := testAlmanaxTemplate
+	empty := ""
+	broken := `{"content": {{.Item.Title}`
+	unknownField := `{"content": "hi", "tts": true}`
+	noMessage := `{"username": "News"}`
+	wrongData := `{"content": {{json .Item.Title}}}`
+	tooLong := `{"content": "` + strings.Repeat("a", discordTemplateMaxLength) + `"}`
+	tooMuchOutput := `{"content": "{{range .Almanax.Days}}` + strings.Repeat("a", discordTemplateMaxOutput/7) + `{{end}}"}`
+
+	assert.Equal(t, "", validateDiscordTemplate(RSSWebhookType, DiscordFormat, nil))
+	assert.Equal(t, "", validateDiscordTemplate(RSSWebhookType, SlackFormat, &empty))
+	assert.Equal(t, "", validateDiscordTemplate(RSSWebhookType, DiscordFormat, &rssTemplate))
+	assert.Equal(t, "", validateDiscordTemplate(AlmanaxWebhookType, DiscordFormat, &almanaxTemplate))
+
+	assert.Equal(t, "Templates are only supported for Discord webhooks.", validateDiscordTemplate(RSSWebhookType, SlackFormat, &rssTemplate))
+	assert.Equal(t, "Templates are only supported for RSS and almanax webhooks.", validateDiscordTemplate(TwitterWebhookType, DiscordFormat, &rssTemplate))
+	assert.Equal(t, "Template must not be longer than 4000 characters.", validateDiscordTemplate(RSSWebhookType, DiscordFormat, &tooLong))
+	assert.True(t, strings.HasPrefix(validateDiscordTemplate(RSSWebhookType, DiscordFormat, &broken), "Template is not valid: "))
+	assert.True(t, strings.HasPrefix(validateDiscordTemplate(RSSWebhookType, DiscordFormat, &unknownField), "Template does not render: rendered message is not a Discord message"))
+	assert.Equal(t, "Template does not render: rendered message has neither content nor embeds", validateDiscordTemplate(RSSWebhookType, DiscordFormat, &noMessage))
+	assert.True(t, strings.HasPrefix(validateDiscordTemplate(AlmanaxWebhookType, DiscordFormat, &wrongData), "Template does not render: "))
+	assert.True(t, strings.HasSuffix(validateDiscordTemplate(AlmanaxWebhookType, DiscordFormat, &tooMuchOutput), "rendered message is too long"))
+}
+
+func TestBuildDiscordHookRssTemplate(t *testing.T) {
+	source := testRssTemplate
+	publishedAt := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	templateHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/template",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+		Template:      &source,
+	}
+	plainHook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/plain",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title:           `Mise à jour "3.1"`,
+			Link:            "https://www.dofus.com/fr/news/1",
+			Description:     "<p>Les serveurs sont <b>fermés</b>.</p>",
+			PublishedParsed: &publishedAt,
+		},
+		Webhooks: []IHook{templateHook, plainHook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-de-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 2)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "New: Mise à ...", *message.Content)
+	assert.Equal(t, "News", message.Username)
+	assert.Equal(t, "https://discord.dofusdude.com/ankama_rss_logo.jpg", message.AvatarUrl)
+	assert.Len(t, message.Embeds, 1)
+	assert.Equal(t, `Mise à jour "3.1"`, *message.Embeds[0].Title)
+	assert.Equal(t, 16711680, message.Embeds[0].Color)
+	assert.Equal(t, "Les serveurs sont **fermés**. (05.03.2024)", *message.Embeds[0].Description)
+
+	var plainMessage DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[1].Body), &plainMessage))
+	assert.Nil(t, plainMessage.Content)
+	assert.Equal(t, 3684408, plainMessage.Embeds[0].Color)
+}
+
+func TestBuildDiscordHookRssTemplateFallsBack(t *testing.T) {
+	source := `{"content": {{json .Item.ImageUrl.Missing}}}`
+	webhook := RssWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/template",
+		Format:        DiscordFormat,
+		PreviewLength: 2000,
+		Template:      &source,
+	}
+
+	hooks, err := BuildHookRss(RssSend{
+		Item: gofeed.Item{
+			Title: "Maintenance",
+			Link:  "https://www.dofus.com/fr/news/1",
+		},
+		Webhooks: []IHook{webhook},
+		Feed:     RssFeed{ApiReadableId: "dofus3-fr-official-news"},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "Maintenance", *message.Embeds[0].Title)
+	assert.Equal(t, 3684408, message.Embeds[0].Color)
+}
+
+func TestBuildDiscordHookAlmanaxTemplate(t *testing.T) {
+	tz := "UTC"
+	source := testAlmanaxTemplate
+	webhook := AlmanaxWebhook{
+		Id:            uuid.New(),
+		Callback:      "https://discord.com/api/webhooks/123/almanax",
+		Format:        DiscordFormat,
+		WantsIsoDate:  true,
+		DailySettings: WebhookDailySettings{Timezone: &tz},
+		Template:      &source,
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	hooks, err := BuildHookAlmanax(testAlmanaxSend(webhook, "daily"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "Almanax", message.Username)
+	assert.Equal(t, "", *message.Content)
+	assert.Equal(t, "daily", *message.Embeds[0].Title)
+	assert.Len(t, message.Embeds[0].Fields, 1)
+	assert.Equal(t, today, message.Embeds[0].Fields[0].Name)
+	assert.Equal(t, "Bonus <"+today+"> 12 345 K 3x More loot & xp", message.Embeds[0].Fields[0].Value)
+
+	hooks, err = BuildHookAlmanax(testAlmanaxSend(webhook, "weekly"))
+	assert.Nil(t, err)
+	assert.Len(t, hooks, 1)
+
+	assert.Nil(t, json.Unmarshal([]byte(hooks[0].Body), &message))
+	assert.Equal(t, "weekly", *message.Embeds[0].Title)
+	assert.Len(t, message.Embeds[0].Fields, 7)
+}
+
+func TestValidateDiscordTemplateBoundsExecution(t *testing.T) {
+	rangeNumber := `{{range 300000000}}{{end}}{"content": "x"}`
+	rangeVariable := `{{$n := 300000000}}{{range $n}}{{end}}{"content": "x"}`
+	rangeFunction := `{{range len .Item.Title}}{{end}}{"content": "x"}`
+	recursion := `{{define "a"}}{{template "a" .}}{{template "a" .}}{{end}}{{template "a" .}}{"content": "x"}`
+	nestedRanges := `{"content": "{{range .Almanax.Days}}{{range $.Almanax.Days}}{{range $.Almanax.Days}}{{end}}{{end}}{{end}}x"}`
+	tooManyNodes := `{"content": "` + strings.Repeat("{{1}}", discordTemplateMaxNodes/3) + `"}`
+
+	for _, source := range []string{rangeNumber, rangeVariable, rangeFunction, recursion, nestedRanges, tooManyNodes} {
+		msg := validateDiscordTemplate(RSSWebhookType, DiscordFormat, &source)
+		assert.True(t, strings.HasPrefix(msg, "Template is not valid: "), source)
+	}
+}
+
+func TestRenderDiscordTemplateTimesOut(t *testing.T) {
+	timeout := discordTemplateTimeout
+	discordTemplateTimeout = 10 * time.Millisecond
+	defer func() { discordTemplateTimeout = timeout }()
+
+	source := `{"content": "{{range .Almanax.Days}}{{range $.Almanax.Days}}{{end}}{{end}}x"}`
+	data := DiscordTemplateData{Almanax: &JsonEventAlmanax{Interval: "monthly", Days: make([]JsonEventAlmanaxDay, 100000)}}
+	_, err := renderDiscordTemplate(source, func(date string) (string, error) { return date, nil }, DiscordWebhook{}, data)
+	assert.Equal(t, errTemplateTimeout, err)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+// discordTestFooter marks test messages, so they are not mistaken for real news in the channel.
+const discordTestFooter = "Test message"
+
+// testMessageLimiter allows a few test messages per webhook id and then one per minute.
+var testMessageLimiter = newKeyedRateLimiter(1.0/60, 3)
+
+// markDiscordTestMessage adds the test footer to the last embed of the message, or a footer only embed if the message
+// has none.
+func markDiscordTestMessage(body string) (string, error) {
+	var discordWebhook DiscordWebhook
+	if err := json.Unmarshal([]byte(body), &discordWebhook); err != nil {
+		return "", err
+	}
+
+	if len(discordWebhook.Embeds) == 0 {
+		discordWebhook.Embeds = append(discordWebhook.Embeds, DiscordEmbed{})
+	}
+
+	last := &discordWebhook.Embeds[len(discordWebhook.Embeds)-1]
+	footer := discordTestFooter
+	if last.Footer != nil && last.Footer.Text != "" {
+		footer += " · " + last.Footer.Text
+	}
+	last.Footer = &DiscordEmbedFooter{Text: footer}
+
+	marked, err := json.Marshal(discordWebhook)
+	if err != nil {
+		return "", err
+	}
+
+	return string(marked), nil
+}
+
+// buildTestRssHook builds the message of the latest item of the first subscription.
+func buildTestRssHook(w http.ResponseWriter, r *http.Request, parsedId uuid.UUID, repo Repository) (PreparedHook, bool) {
+	var err error
+	var socialHook ISocialHook
+	if socialHook, err = repo.GetSocialHook(RSSWebhookType, parsedId); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+
+	webhook := socialHook.(RssWebhook)
+	if webhook.Format != DiscordFormat {
+		http.Error(w, "Test messages are only available for Discord webhooks.", http.StatusBadRequest)
+		return PreparedHook{}, false
+	}
+
+	var feeds []IFeed
+	if feeds, err = repo.GetSocialHookSubscriptions(RSSWebhookType, parsedId); err != nil || len(feeds) == 0 {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+
+	var rssFeed *gofeed.Feed
+	if rssFeed, err = fetchRssFeed(r.Context(), feeds[0].GetRSSUrl()); err != nil {
+		http.Error(w, "Could not read the RSS feed.", http.StatusBadGateway)
+		return PreparedHook{}, false
+	}
+
+	item := latestRssItem(rssFeed.Items)
+	if item == nil {
+		http.Error(w, "The feed has no items yet.", http.StatusNotFound)
+		return PreparedHook{}, false
+	}
+
+	var hooks []PreparedHook
+	if hooks, err = BuildDiscordHookRss(RssSend{
+		Item:     *item,
+		Webhooks: []IHook{webhook},
+		Feed:     feeds[0],
+	}); err != nil || len(hooks) == 0 {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+
+	return hooks[0], true
+}
+
+// buildTestAlmanaxHook builds today's daily message of the first subscription, with the mentions of the webhook.
+func buildTestAlmanaxHook(w http.ResponseWriter, r *http.Request, parsedId uuid.UUID, repo Repository) (PreparedHook, bool) {
+	var err error
+	var webhook AlmanaxWebhook
+	if webhook, err = repo.GetAlmanaxHook(parsedId); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+
+	if webhook.Format != DiscordFormat {
+		http.Error(w, "Test messages are only available for Discord webhooks.", http.StatusBadRequest)
+		return PreparedHook{}, false
+	}
+
+	var feeds []IFeed
+	if feeds, err = repo.GetAlmanaxHookSubscriptions(parsedId); err != nil || len(feeds) == 0 {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+	feed := feeds[0].(AlmanaxFeed)
+
+	tickTime := time.Now()
+	buildInfo := AlmanaxHookBuildInfo{}
+	if buildInfo.almData, err = fetchAlmanaxData(r.Context(), feed.Language, tickTime); err != nil {
+		http.Error(w, "Could not reach Almanax API.", http.StatusBadGateway)
+		return PreparedHook{}, false
+	}
+
+	if buildInfo.translations, err = repo.GetAllWeekdayTranslations(); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+
+	var hooks []PreparedHook
+	if hooks, err = buildDiscordHookAlmanax(AlmanaxSend{
+		Feed:            feed,
+		BuildInfo:       buildInfo,
+		Webhooks:        []IHook{webhook},
+		OnlyPreMentions: []bool{false},
+		IntervalType:    []string{"daily"},
+		TickTime:        tickTime,
+	}); err != nil || len(hooks) == 0 {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return PreparedHook{}, false
+	}
+
+	return hooks[0], true
+}
+
+// handleTestHook sends a test message to the webhook right away and answers with the status code of Discord.
+func handleTestHook(webhookType string, w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(string)
+	parsedId, err := uuid.Parse(id)
+	if err != nil {
+		http.Error(w, "Invalid id.", http.StatusBadRequest)
+		return
+	}
+
+	repo := requestRepository(r)
+
+	var found bool
+	if webhookType == AlmanaxWebhookType {
+		found, err = repo.HasAlmanaxWebhook(parsedId)
+	} else {
+		found, err = repo.HasSocialWebhook(RSSWebhookType, parsedId)
+	}
+	if err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		http.Error(w, "Not found.", http.StatusNotFound)
+		return
+	}
+
+	// limited after the lookup, so unknown ids don't take buckets
+	if ok, wait := testMessageLimiter.allow(parsedId.String()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "Too many test messages.", http.StatusTooManyRequests)
+		return
+	}
+
+	var hook PreparedHook
+	var ok bool
+	if webhookType == AlmanaxWebhookType {
+		hook, ok = buildTestAlmanaxHook(w, r, parsedId, repo)
+	} else {
+		hook, ok = buildTestRssHook(w, r, parsedId, repo)
+	}
+	if !ok {
+		return
+	}
+
+	if hook.Body, err = markDiscordTestMessage(hook.Body); err != nil {
+		http.Error(w, "Internal error.", http.StatusInternalServerError)
+		return
+	}
+
+	// sent past the outbox, but queued behind other messages to the webhook and its rate limits like every delivery
+	callback := webhookClient.Deliver(r.Context(), hook)
+	if callback.StatusCode == 0 {
+		http.Error(w, "Could not reach Discord.", http.StatusBadGateway)
+		return
+	}
+
+	deliveryOut := TestDeliveryDTO{
+		Ok:         callback.Ok,
+		StatusCode: callback.StatusCode,
+		Attempts:   callback.Attempts,
+		LatencyMs:  int(callback.Latency.Milliseconds()),
+	}
+	if !callback.Ok {
+		reason := deliveryError(callback)
+		deliveryOut.Error = &reason
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(deliveryOut); err != nil {
+		http.Error(w, "Error encoding the response.", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkDiscordTestMessage(t *testing.T) {
+	title := "Maintenance"
+	body, err := json.Marshal(DiscordWebhook{
+		Username: "Dofus3 News",
+		Embeds:   []DiscordEmbed{{Title: &title}, {Title: &title, Footer: &DiscordEmbedFooter{Text: "Ankama"}}},
+	})
+	assert.Nil(t, err)
+
+	marked, err := markDiscordTestMessage(string(body))
+	assert.Nil(t, err)
+
+	var message DiscordWebhook
+	assert.Nil(t, json.Unmarshal([]byte(marked), &message))
+	assert.Equal(t, "Dofus3 News", message.Username)
+	assert.Nil(t, message.Embeds[0].Footer)
+	assert.Equal(t, "Test message · Ankama", message.Embeds[1].Footer.Text)
+
+	content := "<@&123>"
+	body, err = json.Marshal(DiscordWebhook{Content: &content})
+	assert.Nil(t, err)
+
+	marked, err = markDiscordTestMessage(string(body))
+	assert.Nil(t, err)
+
+	message = DiscordWebhook{}
+	assert.Nil(t, json.Unmarshal([]byte(marked), &message))
+	assert.Equal(t, content, *message.Content)
+	assert.Len(t, message.Embeds, 1)
+	assert.Equal(t, discordTestFooter, message.Embeds[0].Footer.Text)
+}
+
+func TestHandleTestHookInvalidId(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/webhooks/rss/nope/test", nil)
+	handleTestRss(recorder, request.WithContext(context.WithValue(request.Context(), "id", "nope")))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "Invalid id.", strings.TrimSpace(recorder.Body.String()))
+}
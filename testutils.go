@@ -30,6 +30,26 @@ func testutilCleartables() error {
 	}
 	defer conn.Close()
 
+	_, err = conn.Exec(ctx, "delete from feed_states")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, "delete from api_keys")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, "delete from outbox")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, "delete from discord_messages")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, "delete from delivery_attempts")
+	if err != nil {
+		return err
+	}
 	_, err = conn.Exec(ctx, "delete from almanax_mentions")
 	if err != nil {
 		return err
@@ -61,3 +81,17 @@ func testutilCleartables() error {
 
 	return err
 }
+
+func testutilGetOutboxStatus(id int64) (string, int, error) {
+	ctx := context.Background()
+	var err error
+	var conn *pgxpool.Pool
+	if conn, err = pgxpool.New(ctx, PostgresUrl); err != nil {
+		return "", 0, err
+	}
+	defer conn.Close()
+	var status string
+	var attempts int
+	err = conn.QueryRow(ctx, "select status, attempts from outbox where id = $1", id).Scan(&status, &attempts)
+	return status, attempts, err
+}
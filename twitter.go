@@ -28,6 +28,16 @@ func handlePutTwitter(w http.ResponseWriter, r *http.Request) {
 	handlePutSocial(TwitterWebhookType, w, r)
 }
 
+func handleGetTwitterDeliveries(w http.ResponseWriter, r *http.Request) {
+	metricsIncSocialCRUD(TwitterWebhookType)
+	handleGetDeliveries(TwitterWebhookType, w, r)
+}
+
+func handleRestoreTwitter(w http.ResponseWriter, r *http.Request) {
+	metricsIncSocialCRUD(TwitterWebhookType)
+	handleRestoreHook(TwitterWebhookType, w, r)
+}
+
 func handleCreateTwitterHook(w http.ResponseWriter, r *http.Request) {
 	metricsIncSocialCRUD(TwitterWebhookType)
 	handleCreateSocial(TwitterWebhookType, w, r)
@@ -153,6 +163,7 @@ func HandleTimeTwitter(socialFeed IFeed, _ TwitterState, tickTime time.Time, tic
 			twitterSends = append(twitterSends, TwitterSend{
 				Tweet:    tweet,
 				Webhooks: webhooksToSend,
+				Feed:     socialFeed,
 			})
 		}
 
@@ -181,21 +192,69 @@ func BuildDiscordHookTwitter(twitterHook TwitterSend) ([]PreparedHook, error) {
 			}
 		}
 
+		webhook.GetDiscordStyle().apply(&discordWebhook)
+
 		jsonBody, err := json.Marshal(discordWebhook)
 		if err != nil {
 			return nil, err
 		}
 
 		res = append(res, PreparedHook{
-			Callback: webhook.GetCallback(),
-			Body:     string(jsonBody),
+			WebhookId: webhook.GetId(),
+			Callback:  discordThreadCallback(webhook.GetCallback(), webhook.GetDiscordThread()),
+			Body:      string(jsonBody),
 		})
 	}
 
 	return res, nil
 }
 
-func ListenTwitter(ctx context.Context, feed IFeed) {
+// BuildHookTwitter builds every webhook in the format it was created with.
+func BuildHookTwitter(twitterHook TwitterSend) ([]PreparedHook, error) {
+	var res []PreparedHook
+
+	discordBuild := twitterHook
+	discordBuild.Webhooks = nil
+	for _, webhook := range twitterHook.Webhooks {
+		switch webhook.GetFormat() {
+		case JsonFormat:
+			hook, err := buildJsonHookTwitter(twitterHook, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case SlackFormat:
+			hook, err := buildSlackHookTwitter(twitterHook, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case TelegramFormat:
+			hook, err := buildTelegramHookTwitter(twitterHook, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		case MatrixFormat:
+			hook, err := buildMatrixHookTwitter(twitterHook, webhook)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, hook)
+		default:
+			discordBuild.Webhooks = append(discordBuild.Webhooks, webhook)
+		}
+	}
+
+	discordHooks, err := BuildDiscordHookTwitter(discordBuild)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(res, discordHooks...), nil
+}
+
+func ListenTwitter(ctx context.Context, repo Repository, feed IFeed) {
 	var state TwitterState
-	Listen(ctx, TwitterPollingRate, feed, state, HandleTimeTwitter, BuildDiscordHookTwitter)
+	Listen(ctx, repo, TwitterPollingRate, feed, state, HandleTimeTwitter, BuildHookTwitter)
 }
@@ -170,13 +170,13 @@ type TwitterTestSuite struct {
 func (suite *TwitterTestSuite) SetupSuite() {
 	ReadEnvs()
 
-	var repo Repository
-	if err := repo.Init(context.Background()); err != nil {
+	pool, err := NewPool(context.Background())
+	if err != nil {
 		suite.T().Fatal(err)
 	}
 
-	suite.db = repo
-	suite.sut = httptest.NewServer(Router())
+	suite.db = NewRepository(context.Background(), pool)
+	suite.sut = httptest.NewServer(Router(suite.db))
 	suite.discordCheck = append(suite.discordCheck, apitest.NewMock().
 		Get("https://discord.com/api/webhooks/123/abc").
 		RespondWith().
@@ -213,7 +213,7 @@ func (suite *TwitterTestSuite) Test_Feeds_Db() {
 
 func (suite *TwitterTestSuite) Test_Feeds() {
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/meta/webhooks/twitter").
 		Expect(suite.T()).
 		Status(http.StatusOK).
@@ -239,7 +239,7 @@ func (suite *TwitterTestSuite) Test_Feeds() {
 func (suite *TwitterTestSuite) Test_GetFeeds() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -275,7 +275,7 @@ func (suite *TwitterTestSuite) Test_GetFeeds() {
 func (suite *TwitterTestSuite) Test_CRUD_Create() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -295,7 +295,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[1]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc1", // callback ok but no subscriptions
@@ -307,7 +307,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc3",
@@ -321,7 +321,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc", // double callback
@@ -336,7 +336,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[3]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc3",
@@ -350,7 +350,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 		End()
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		Expect(suite.T()).
 		Status(http.StatusBadRequest). // no body
@@ -358,7 +358,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[5]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc5",
@@ -391,7 +391,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 
 	apitest.New().
 		Mocks(suite.discordCheck[6]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc6",
@@ -422,7 +422,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create() {
 func (suite *TwitterTestSuite) Test_CRUD_Create_And_Get() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -444,7 +444,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create_And_Get() {
 	assert.Nil(suite.T(), err)
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/webhooks/twitter/" + id.String()).
 		Expect(suite.T()).
 		Status(http.StatusOK).
@@ -462,7 +462,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create_And_Get() {
 func (suite *TwitterTestSuite) Test_CRUD_Delete() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -484,14 +484,14 @@ func (suite *TwitterTestSuite) Test_CRUD_Delete() {
 	assert.Nil(suite.T(), err)
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Delete("/webhooks/twitter/" + id.String()).
 		Expect(suite.T()).
 		Status(http.StatusNoContent).
 		End()
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Get("/webhooks/twitter/" + id.String()).
 		Expect(suite.T()).
 		Status(http.StatusNotFound).
@@ -501,7 +501,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Delete() {
 func (suite *TwitterTestSuite) Test_CRUD_Create_And_Update() {
 	apitest.New().
 		Mocks(suite.discordCheck[0]).
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Post("/webhooks/twitter").
 		JSON(SocialHookCreate{
 			Callback: "https://discord.com/api/webhooks/123/abc",
@@ -526,7 +526,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create_And_Update() {
 	assert.Nil(suite.T(), err)
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/twitter/" + id.String()).
 		JSON(SocialWebhookPut{
 			Subscriptions: []string{
@@ -554,7 +554,7 @@ func (suite *TwitterTestSuite) Test_CRUD_Create_And_Update() {
 		End()
 
 	apitest.New().
-		Handler(Router()).
+		Handler(Router(suite.db)).
 		Put("/webhooks/twitter/" + id.String()).
 		JSON(SocialWebhookPut{
 			Subscriptions: []string{
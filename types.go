@@ -16,13 +16,37 @@ const (
 )
 
 type RssState struct {
-	LastHash uint64
+	SeenItems []RssSeenItem `json:"seen_items"`
+	restored  bool
+	// pending is the state after the current tick. It is only saved and applied once its deliveries are enqueued.
+	pending *FeedStateUpdate
+}
+
+// FeedStateUpdate is the new state of a feed, saved in the same transaction as the deliveries it causes.
+type FeedStateUpdate struct {
+	FeedId uint64
+	State  RssState
+}
+
+type RssSeenItem struct {
+	Key  string `json:"key"`
+	Hash uint64 `json:"hash"`
 }
 
 type RssSend struct {
 	Item     gofeed.Item
+	ItemKey  string
+	Updated  bool
 	Webhooks []IHook
 	Feed     IFeed
+	// Messages are the Discord messages already posted for the item, by webhook. Updates edit them.
+	Messages map[uuid.UUID]DiscordMessageRef
+}
+
+// DiscordMessageRef points to a message posted by a Discord webhook. The channel is the thread for forum posts.
+type DiscordMessageRef struct {
+	MessageId string `json:"id"`
+	ChannelId string `json:"channel_id"`
 }
 
 type TwitterState struct {
@@ -32,16 +56,32 @@ type TwitterState struct {
 type TwitterSend struct {
 	Tweet    Tweet
 	Webhooks []IHook
+	Feed     IFeed
 }
 
 type PreparedHook struct {
-	Callback string
-	Body     string
+	OutboxId  int64
+	WebhookId uuid.UUID
+	FeedId    uint64
+	Callback  string
+	Body      string
+	Secret    string
+	// AccessToken authorizes the request for targets that are not called by URL only. Matrix gets it as bearer token,
+	// Telegram as bot token in the URL. It is never stored with the callback.
+	AccessToken string
+	// ItemKey is set for Discord messages of RSS items, their message id is stored to edit them later.
+	ItemKey string
 }
 
 type SendCallbackReturn struct {
-	Callback string
-	Ok       bool
+	Callback   string
+	Ok         bool
+	Permanent  bool
+	StatusCode int
+	Attempts   int
+	Latency    time.Duration
+	Err        error
+	Message    *DiscordMessageRef
 }
 
 type AlmanaxSend struct {
@@ -112,6 +152,10 @@ type DiscordEmbedField struct {
 	Inline bool   `json:"inline"`
 }
 
+type DiscordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
 type DiscordEmbed struct {
 	Title       *string             `json:"title"`
 	Color       int                 `json:"color"`
@@ -120,6 +164,7 @@ type DiscordEmbed struct {
 	Fields      []DiscordEmbedField `json:"fields"`
 	Url         *string             `json:"url"`
 	Description *string             `json:"description"`
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
 }
 
 type DiscordWebhook struct {
@@ -128,13 +173,93 @@ type DiscordWebhook struct {
 	Username    string         `json:"username"`
 	AvatarUrl   string         `json:"avatar_url"`
 	Attachments []string       `json:"attachments"`
+	ThreadName  string         `json:"thread_name,omitempty"`
+	AppliedTags []string       `json:"applied_tags,omitempty"`
+}
+
+// DiscordThread makes a Discord webhook post into an existing thread, or create a forum post for every RSS item.
+type DiscordThread struct {
+	ThreadId  *string           `json:"thread_id,omitempty"`
+	Forum     bool              `json:"forum,omitempty"`
+	ForumTags map[string]string `json:"forum_tags,omitempty"`
+}
+
+// DiscordStyle overrides the username, avatar and embed color of Discord messages. Unset fields keep the built-in ones.
+type DiscordStyle struct {
+	Username   *string
+	AvatarUrl  *string
+	EmbedColor *int
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type SlackImage struct {
+	Type     string `json:"type"`
+	ImageUrl string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+type SlackBlock struct {
+	Type      string      `json:"type"`
+	Text      *SlackText  `json:"text,omitempty"`
+	Fields    []SlackText `json:"fields,omitempty"`
+	Elements  []SlackText `json:"elements,omitempty"`
+	Accessory *SlackImage `json:"accessory,omitempty"`
+	ImageUrl  string      `json:"image_url,omitempty"`
+	AltText   string      `json:"alt_text,omitempty"`
+}
+
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+type TelegramTarget struct {
+	BotToken string `json:"bot_token"`
+	ChatId   string `json:"chat_id"`
+}
+
+type TelegramMessage struct {
+	ChatId    string `json:"chat_id"`
+	Text      string `json:"text,omitempty"`
+	Photo     string `json:"photo,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode"`
+}
+
+type MatrixTarget struct {
+	Homeserver  string `json:"homeserver"`
+	RoomId      string `json:"room_id"`
+	AccessToken string `json:"access_token"`
+}
+
+type MatrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+type WebhookTarget struct {
+	Format   string
+	Callback string
+	Telegram *TelegramTarget
+	Matrix   *MatrixTarget
 }
 
 type SocialWebhookPut struct {
-	Whitelist     []string `json:"whitelist"`
-	Blacklist     []string `json:"blacklist"`
-	Subscriptions []string `json:"subscriptions"`
-	PreviewLength *int     `json:"preview_length"`
+	Whitelist     []string       `json:"whitelist"`
+	Blacklist     []string       `json:"blacklist"`
+	Subscriptions []string       `json:"subscriptions"`
+	PreviewLength *int           `json:"preview_length"`
+	DiscordThread *DiscordThread `json:"discord_thread"`
+	Template      *string        `json:"template"`
+	Username      *string        `json:"username"`
+	AvatarUrl     *string        `json:"avatar_url"`
+	EmbedColor    *int           `json:"embed_color"`
 }
 
 type WebhookJobs struct {
@@ -142,8 +267,10 @@ type WebhookJobs struct {
 }
 
 type WebhookJob struct {
-	Url  string `json:"url"`
-	Body string `json:"json_body"`
+	Url     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Body    string            `json:"json_body"`
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 type HookMeta struct {
@@ -163,10 +290,18 @@ type AlmanaxHookDTO struct {
 	BonusWhitelist []string                 `json:"bonus_whitelist"`
 	BonusBlacklist []string                 `json:"bonus_blacklist"`
 	Format         string                   `json:"format"`
+	Secret         *string                  `json:"secret,omitempty"`
 	WantsIsoDate   bool                     `json:"iso_date"`
 	Intervals      []string                 `json:"intervals"`
 	WeeklyWeekday  *string                  `json:"weekly_weekday"`
 	Mentions       *map[string][]MentionDTO `json:"mentions"`
+	DiscordThread  *DiscordThread           `json:"discord_thread,omitempty"`
+	Template       *string                  `json:"template,omitempty"`
+	Username       *string                  `json:"username,omitempty"`
+	AvatarUrl      *string                  `json:"avatar_url,omitempty"`
+	EmbedColor     *int                     `json:"embed_color,omitempty"`
+	FailureCount   int                      `json:"failure_count"`
+	DisabledReason *string                  `json:"disabled_reason"`
 	CreatedAt      time.Time                `json:"created_at"`
 	UpdatedAt      time.Time                `json:"updated_at"`
 }
@@ -176,6 +311,13 @@ type AlmanaxHookPost struct {
 	BonusBlacklist []string                 `json:"bonus_blacklist"`
 	DailySettings  *WebhookDailySettings    `json:"daily_settings"`
 	Callback       string                   `json:"callback"`
+	Telegram       *TelegramTarget          `json:"telegram"`
+	Matrix         *MatrixTarget            `json:"matrix"`
+	DiscordThread  *DiscordThread           `json:"discord_thread"`
+	Template       *string                  `json:"template"`
+	Username       *string                  `json:"username"`
+	AvatarUrl      *string                  `json:"avatar_url"`
+	EmbedColor     *int                     `json:"embed_color"`
 	Subscriptions  []string                 `json:"subscriptions"`
 	WantsIsoDate   *bool                    `json:"iso_date"`
 	Format         string                   `json:"format"`
@@ -201,6 +343,13 @@ type IFeed interface {
 type IHook interface {
 	GetId() uuid.UUID
 	GetCallback() string
+	GetFormat() string
+	GetSecret() string
+	GetTelegramTarget() *TelegramTarget
+	GetMatrixTarget() *MatrixTarget
+	GetDiscordThread() *DiscordThread
+	GetTemplate() *string
+	GetDiscordStyle() DiscordStyle
 	GetPreviewLength() int
 	IsWantIsoDate() bool
 	GetTimezone() string
@@ -319,20 +468,33 @@ type WebhookDailySettings struct {
 }
 
 type AlmanaxWebhook struct {
-	Id             uuid.UUID
-	DailySettings  WebhookDailySettings
-	BonusBlacklist []string
-	BonusWhitelist []string
-	Subscriptions  []Subscription
-	Callback       string
-	Format         string
-	WantsIsoDate   bool
-	Mentions       *map[string][]MentionDTO
-	Intervals      []string
-	WeeklyWeekday  *string
-	LastFiredAt    *time.Time
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	Id                uuid.UUID
+	DailySettings     WebhookDailySettings
+	BonusBlacklist    []string
+	BonusWhitelist    []string
+	Subscriptions     []Subscription
+	Callback          string
+	Format            string
+	WantsIsoDate      bool
+	Mentions          *map[string][]MentionDTO
+	Intervals         []string
+	WeeklyWeekday     *string
+	LastFiredAt       *time.Time
+	FailureCount      int
+	DisabledReason    *string
+	Secret            *string
+	TelegramBotToken  *string
+	TelegramChatId    *string
+	MatrixHomeserver  *string
+	MatrixRoomId      *string
+	MatrixAccessToken *string
+	DiscordThread     *DiscordThread
+	Template          *string
+	DiscordUsername   *string
+	DiscordAvatarUrl  *string
+	DiscordEmbedColor *int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 func (a AlmanaxWebhook) GetMentions() *map[string][]MentionDTO {
@@ -354,6 +516,37 @@ func (a AlmanaxWebhook) GetCallback() string {
 	return a.Callback
 }
 
+func (a AlmanaxWebhook) GetFormat() string {
+	return a.Format
+}
+
+func (a AlmanaxWebhook) GetSecret() string {
+	if a.Secret == nil {
+		return ""
+	}
+	return *a.Secret
+}
+
+func (a AlmanaxWebhook) GetTelegramTarget() *TelegramTarget {
+	return newTelegramTarget(a.TelegramBotToken, a.TelegramChatId)
+}
+
+func (a AlmanaxWebhook) GetMatrixTarget() *MatrixTarget {
+	return newMatrixTarget(a.MatrixHomeserver, a.MatrixRoomId, a.MatrixAccessToken)
+}
+
+func (a AlmanaxWebhook) GetDiscordThread() *DiscordThread {
+	return a.DiscordThread
+}
+
+func (a AlmanaxWebhook) GetTemplate() *string {
+	return a.Template
+}
+
+func (a AlmanaxWebhook) GetDiscordStyle() DiscordStyle {
+	return DiscordStyle{Username: a.DiscordUsername, AvatarUrl: a.DiscordAvatarUrl, EmbedColor: a.DiscordEmbedColor}
+}
+
 func (a AlmanaxWebhook) GetPreviewLength() int {
 	return 0
 }
@@ -363,21 +556,42 @@ func (a AlmanaxWebhook) IsWantIsoDate() bool {
 }
 
 type TwitterWebhook struct {
-	Id            uuid.UUID  `json:"id"`
-	Callback      string     `json:"-"`
-	Whitelist     []string   `json:"bonus_whitelist"`
-	Blacklist     []string   `json:"bonus_blacklist"`
-	Format        string     `json:"format"`
-	LastFiredAt   *time.Time `json:"last_fired_at"`
-	PreviewLength int        `json:"preview_length"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	Id                uuid.UUID      `json:"id"`
+	Callback          string         `json:"-"`
+	Secret            *string        `json:"-"`
+	TelegramBotToken  *string        `json:"-"`
+	TelegramChatId    *string        `json:"-"`
+	MatrixHomeserver  *string        `json:"-"`
+	MatrixRoomId      *string        `json:"-"`
+	MatrixAccessToken *string        `json:"-"`
+	DiscordThread     *DiscordThread `json:"-"`
+	Template          *string        `json:"-"`
+	DiscordUsername   *string        `json:"-"`
+	DiscordAvatarUrl  *string        `json:"-"`
+	DiscordEmbedColor *int           `json:"-"`
+	Whitelist         []string       `json:"bonus_whitelist"`
+	Blacklist         []string       `json:"bonus_blacklist"`
+	Format            string         `json:"format"`
+	LastFiredAt       *time.Time     `json:"last_fired_at"`
+	PreviewLength     int            `json:"preview_length"`
+	FailureCount      int            `json:"failure_count"`
+	DisabledReason    *string        `json:"disabled_reason"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
 }
 
 func (s TwitterWebhook) GetLastFiredAt() *time.Time {
 	return s.LastFiredAt
 }
 
+func (s TwitterWebhook) GetFailureCount() int {
+	return s.FailureCount
+}
+
+func (s TwitterWebhook) GetDisabledReason() *string {
+	return s.DisabledReason
+}
+
 func (s TwitterWebhook) GetCreatedAt() time.Time {
 	return s.CreatedAt
 }
@@ -390,6 +604,33 @@ func (s TwitterWebhook) GetFormat() string {
 	return s.Format
 }
 
+func (s TwitterWebhook) GetSecret() string {
+	if s.Secret == nil {
+		return ""
+	}
+	return *s.Secret
+}
+
+func (s TwitterWebhook) GetTelegramTarget() *TelegramTarget {
+	return newTelegramTarget(s.TelegramBotToken, s.TelegramChatId)
+}
+
+func (s TwitterWebhook) GetMatrixTarget() *MatrixTarget {
+	return newMatrixTarget(s.MatrixHomeserver, s.MatrixRoomId, s.MatrixAccessToken)
+}
+
+func (s TwitterWebhook) GetDiscordThread() *DiscordThread {
+	return s.DiscordThread
+}
+
+func (s TwitterWebhook) GetTemplate() *string {
+	return s.Template
+}
+
+func (s TwitterWebhook) GetDiscordStyle() DiscordStyle {
+	return DiscordStyle{Username: s.DiscordUsername, AvatarUrl: s.DiscordAvatarUrl, EmbedColor: s.DiscordEmbedColor}
+}
+
 func (s TwitterWebhook) GetMentions() *map[string][]MentionDTO {
 	return nil
 }
@@ -427,21 +668,42 @@ func (s TwitterWebhook) IsWantIsoDate() bool {
 }
 
 type RssWebhook struct {
-	Id            uuid.UUID  `json:"id"`
-	Callback      string     `json:"-"`
-	Whitelist     []string   `json:"bonus_whitelist"`
-	Blacklist     []string   `json:"bonus_blacklist"`
-	Format        string     `json:"format"`
-	LastFiredAt   *time.Time `json:"last_fired_at"`
-	PreviewLength int        `json:"preview_length"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	Id                uuid.UUID      `json:"id"`
+	Callback          string         `json:"-"`
+	Secret            *string        `json:"-"`
+	TelegramBotToken  *string        `json:"-"`
+	TelegramChatId    *string        `json:"-"`
+	MatrixHomeserver  *string        `json:"-"`
+	MatrixRoomId      *string        `json:"-"`
+	MatrixAccessToken *string        `json:"-"`
+	DiscordThread     *DiscordThread `json:"-"`
+	Template          *string        `json:"-"`
+	DiscordUsername   *string        `json:"-"`
+	DiscordAvatarUrl  *string        `json:"-"`
+	DiscordEmbedColor *int           `json:"-"`
+	Whitelist         []string       `json:"bonus_whitelist"`
+	Blacklist         []string       `json:"bonus_blacklist"`
+	Format            string         `json:"format"`
+	LastFiredAt       *time.Time     `json:"last_fired_at"`
+	PreviewLength     int            `json:"preview_length"`
+	FailureCount      int            `json:"failure_count"`
+	DisabledReason    *string        `json:"disabled_reason"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
 }
 
 func (s RssWebhook) GetLastFiredAt() *time.Time {
 	return s.LastFiredAt
 }
 
+func (s RssWebhook) GetFailureCount() int {
+	return s.FailureCount
+}
+
+func (s RssWebhook) GetDisabledReason() *string {
+	return s.DisabledReason
+}
+
 func (s RssWebhook) GetCreatedAt() time.Time {
 	return s.CreatedAt
 }
@@ -454,6 +716,33 @@ func (s RssWebhook) GetFormat() string {
 	return s.Format
 }
 
+func (s RssWebhook) GetSecret() string {
+	if s.Secret == nil {
+		return ""
+	}
+	return *s.Secret
+}
+
+func (s RssWebhook) GetTelegramTarget() *TelegramTarget {
+	return newTelegramTarget(s.TelegramBotToken, s.TelegramChatId)
+}
+
+func (s RssWebhook) GetMatrixTarget() *MatrixTarget {
+	return newMatrixTarget(s.MatrixHomeserver, s.MatrixRoomId, s.MatrixAccessToken)
+}
+
+func (s RssWebhook) GetDiscordThread() *DiscordThread {
+	return s.DiscordThread
+}
+
+func (s RssWebhook) GetTemplate() *string {
+	return s.Template
+}
+
+func (s RssWebhook) GetDiscordStyle() DiscordStyle {
+	return DiscordStyle{Username: s.DiscordUsername, AvatarUrl: s.DiscordAvatarUrl, EmbedColor: s.DiscordEmbedColor}
+}
+
 func (s RssWebhook) GetMentions() *map[string][]MentionDTO {
 	return nil
 }
@@ -500,6 +789,11 @@ type ISocialHook interface {
 	GetPreviewLength() int
 	GetBlacklist() []string
 	GetWhitelist() []string
+	GetFailureCount() int
+	GetDisabledReason() *string
+	GetDiscordThread() *DiscordThread
+	GetTemplate() *string
+	GetDiscordStyle() DiscordStyle
 }
 
 type ISocialHookUpdate interface {
@@ -508,15 +802,26 @@ type ISocialHookUpdate interface {
 	GetWhitelist() []string
 	GetSubscriptions() []string
 	GetPreviewLength() *int
+	GetDiscordThread() *DiscordThread
+	GetTemplate() *string
+	GetDiscordStyle() DiscordStyle
 }
 
 type SocialHookCreate struct {
-	Whitelist     []string `json:"whitelist"`
-	Blacklist     []string `json:"blacklist"`
-	Subscriptions []string `json:"subscriptions"`
-	PreviewLength *int     `json:"preview_length"`
-	Callback      string   `json:"callback"`
-	Format        string   `json:"format"`
+	Whitelist     []string        `json:"whitelist"`
+	Blacklist     []string        `json:"blacklist"`
+	Subscriptions []string        `json:"subscriptions"`
+	PreviewLength *int            `json:"preview_length"`
+	Callback      string          `json:"callback"`
+	Format        string          `json:"format"`
+	Telegram      *TelegramTarget `json:"telegram"`
+	Matrix        *MatrixTarget   `json:"matrix"`
+	DiscordThread *DiscordThread  `json:"discord_thread"`
+	Template      *string         `json:"template"`
+	Username      *string         `json:"username"`
+	AvatarUrl     *string         `json:"avatar_url"`
+	EmbedColor    *int            `json:"embed_color"`
+	Secret        *string         `json:"-"`
 }
 
 type MentionDTO struct {
@@ -534,6 +839,11 @@ type AlmanaxHookPut struct {
 	Mentions       *map[string][]MentionDTO `json:"mentions"`
 	Intervals      []string                 `json:"intervals"`
 	WeeklyWeekday  *string                  `json:"weekly_weekday"`
+	DiscordThread  *DiscordThread           `json:"discord_thread"`
+	Template       *string                  `json:"template"`
+	Username       *string                  `json:"username"`
+	AvatarUrl      *string                  `json:"avatar_url"`
+	EmbedColor     *int                     `json:"embed_color"`
 }
 
 type CreateAlmanaxHook struct {
@@ -547,26 +857,112 @@ type CreateAlmanaxHook struct {
 	Mentions       *map[string][]MentionDTO
 	Intervals      []string
 	WeeklyWeekday  *string
+	Secret         *string
+	Telegram       *TelegramTarget
+	Matrix         *MatrixTarget
+	DiscordThread  *DiscordThread
+	Template       *string
+	Username       *string
+	AvatarUrl      *string
+	EmbedColor     *int
 }
 
 type SocialWebhookDTO struct {
-	Id            uuid.UUID  `json:"id"`
-	Whitelist     []string   `json:"whitelist"`
-	Blacklist     []string   `json:"blacklist"`
-	Subscriptions []string   `json:"subscriptions"`
-	Format        string     `json:"format"`
-	PreviewLength int        `json:"preview_length"`
-	CreatedAt     time.Time  `json:"created_at"`
-	LastFiredAt   *time.Time `json:"last_fired_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	Id             uuid.UUID      `json:"id"`
+	Whitelist      []string       `json:"whitelist"`
+	Blacklist      []string       `json:"blacklist"`
+	Subscriptions  []string       `json:"subscriptions"`
+	Format         string         `json:"format"`
+	Secret         *string        `json:"secret,omitempty"`
+	PreviewLength  int            `json:"preview_length"`
+	DiscordThread  *DiscordThread `json:"discord_thread,omitempty"`
+	Template       *string        `json:"template,omitempty"`
+	Username       *string        `json:"username,omitempty"`
+	AvatarUrl      *string        `json:"avatar_url,omitempty"`
+	EmbedColor     *int           `json:"embed_color,omitempty"`
+	FailureCount   int            `json:"failure_count"`
+	DisabledReason *string        `json:"disabled_reason"`
+	CreatedAt      time.Time      `json:"created_at"`
+	LastFiredAt    *time.Time     `json:"last_fired_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+type DeliveryAttemptDTO struct {
+	Id         int64     `json:"id"`
+	Feed       *string   `json:"feed"`
+	Ok         bool      `json:"ok"`
+	StatusCode *int      `json:"status_code"`
+	Attempts   int       `json:"attempts"`
+	LatencyMs  int       `json:"latency_ms"`
+	Error      *string   `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TestDeliveryDTO is the answer of the target to a test message.
+type TestDeliveryDTO struct {
+	Ok         bool    `json:"ok"`
+	StatusCode int     `json:"status_code"`
+	Attempts   int     `json:"attempts"`
+	LatencyMs  int     `json:"latency_ms"`
+	Error      *string `json:"error"`
+}
+
+type DeliveryAttemptsPageDTO struct {
+	Deliveries []DeliveryAttemptDTO `json:"deliveries"`
+	Page       int                  `json:"page"`
+	PageSize   int                  `json:"page_size"`
+	Total      int                  `json:"total"`
+}
+
+type AdminFeedDTO struct {
+	Id         uint64    `json:"id"`
+	Type       string    `json:"type"`
+	Name       string    `json:"name"`
+	Url        *string   `json:"url"`
+	TwitterId  *uint64   `json:"twitter_id"`
+	Language   *string   `json:"language"`
+	IsOfficial bool      `json:"official"`
+	Disabled   bool      `json:"disabled"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AdminFeedPost struct {
+	Name       string `json:"name"`
+	Url        string `json:"url"`
+	TwitterId  uint64 `json:"twitter_id"`
+	Language   string `json:"language"`
+	IsOfficial bool   `json:"official"`
+}
+
+type AdminFeedPut struct {
+	Name     *string `json:"name"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type ApiKey struct {
+	Id         uuid.UUID
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (k ApiKey) HasScope(scope string) bool {
+	return sliceContains(k.Scopes, scope)
 }
 
 type SocialWebhookPutDb struct {
 	Id            uuid.UUID
-	Whitelist     []string `json:"whitelist"`
-	Blacklist     []string `json:"blacklist"`
-	Subscriptions []string `json:"subscriptions"`
-	PreviewLength *int     `json:"preview_length"`
+	Whitelist     []string       `json:"whitelist"`
+	Blacklist     []string       `json:"blacklist"`
+	Subscriptions []string       `json:"subscriptions"`
+	PreviewLength *int           `json:"preview_length"`
+	DiscordThread *DiscordThread `json:"discord_thread"`
+	Template      *string        `json:"template"`
+	Username      *string        `json:"username"`
+	AvatarUrl     *string        `json:"avatar_url"`
+	EmbedColor    *int           `json:"embed_color"`
 }
 
 func (hook SocialWebhookPutDb) GetId() uuid.UUID {
@@ -588,3 +984,70 @@ func (hook SocialWebhookPutDb) GetSubscriptions() []string {
 func (hook SocialWebhookPutDb) GetPreviewLength() *int {
 	return hook.PreviewLength
 }
+
+func (hook SocialWebhookPutDb) GetDiscordThread() *DiscordThread {
+	return hook.DiscordThread
+}
+
+func (hook SocialWebhookPutDb) GetTemplate() *string {
+	return hook.Template
+}
+
+func (hook SocialWebhookPutDb) GetDiscordStyle() DiscordStyle {
+	return DiscordStyle{Username: hook.Username, AvatarUrl: hook.AvatarUrl, EmbedColor: hook.EmbedColor}
+}
+
+type JsonEvent struct {
+	Id        uuid.UUID         `json:"id"`
+	Type      string            `json:"type"`
+	Feed      string            `json:"feed"`
+	CreatedAt time.Time         `json:"created_at"`
+	Item      *JsonEventItem    `json:"item,omitempty"`
+	Almanax   *JsonEventAlmanax `json:"almanax,omitempty"`
+}
+
+type JsonEventItem struct {
+	Id          string     `json:"id"`
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	Author      string     `json:"author"`
+	Description string     `json:"description"`
+	ImageUrl    *string    `json:"image_url"`
+	PublishedAt *time.Time `json:"published_at"`
+	Updated     bool       `json:"updated"`
+}
+
+type JsonEventAlmanax struct {
+	Interval string                `json:"interval"`
+	Days     []JsonEventAlmanaxDay `json:"days"`
+}
+
+type JsonEventAlmanaxDay struct {
+	Date        string                  `json:"date"`
+	Bonus       JsonEventAlmanaxBonus   `json:"bonus"`
+	Tribute     JsonEventAlmanaxTribute `json:"tribute"`
+	RewardKamas int32                   `json:"reward_kamas"`
+}
+
+type JsonEventAlmanaxBonus struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type JsonEventAlmanaxTribute struct {
+	AnkamaId int32  `json:"ankama_id"`
+	Name     string `json:"name"`
+	Quantity int32  `json:"quantity"`
+	ImageUrl string `json:"image_url"`
+}
+
+// DiscordTemplateData is what the template of a Discord webhook is rendered with. Item is set for RSS webhooks,
+// Almanax, Mentions and Previews for almanax webhooks.
+type DiscordTemplateData struct {
+	Feed     string
+	Item     *JsonEventItem
+	Almanax  *JsonEventAlmanax
+	Mentions string
+	Previews []DiscordEmbedField
+}
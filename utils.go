@@ -2,7 +2,9 @@ package main
 
 import (
 	"log"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,15 +12,31 @@ import (
 )
 
 var (
-	ApiPort             string
-	TwitterToken        string
-	PostgresUrl         string
-	ServerTz            string
-	ServerlessSenderUrl string
-	RssPollingRate      time.Duration
-	TwitterPollingRate  time.Duration
-	AlmanaxPollingRate  time.Duration
-	SendBatchEnabled    bool
+	ApiPort                  string
+	TwitterToken             string
+	PostgresUrl              string
+	ServerTz                 string
+	ServerlessSenderUrl      string
+	RssPollingRate           time.Duration
+	TwitterPollingRate       time.Duration
+	AlmanaxPollingRate       time.Duration
+	SendBatchEnabled         bool
+	RssSendUpdates           bool
+	DeliveryRetryPolicy      RetryPolicy
+	OutboxPollingRate        time.Duration
+	OutboxLease              time.Duration
+	OutboxMaxAttempts        int
+	OutboxRetention          time.Duration
+	DeliveryHistoryRetention time.Duration
+	WebhookFailureThreshold  int
+	WebhookFailureWindow     time.Duration
+	WebhookRetention         time.Duration
+	PostgresPoolSize         int32
+	FeedReloadRate           time.Duration
+	ShutdownTimeout          time.Duration
+	RateLimitRps             float64
+	RateLimitBurst           int
+	TrustedProxies           []*net.IPNet
 )
 
 func ReadEnvs() {
@@ -39,11 +57,69 @@ func ReadEnvs() {
 	if AlmanaxPollingRate, err = time.ParseDuration(getEnv("ALMANAX_POLLING_RATE", "1m")); err != nil {
 		log.Fatal("could not convert ALMANAX_POLLING_RATE", err)
 	}
+	if FeedReloadRate, err = time.ParseDuration(getEnv("FEED_RELOAD_RATE", "1m")); err != nil {
+		log.Fatal("could not convert FEED_RELOAD_RATE", err)
+	}
+	if ShutdownTimeout, err = time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s")); err != nil {
+		log.Fatal("could not convert SHUTDOWN_TIMEOUT", err)
+	}
+	if RssSendUpdates, err = strconv.ParseBool(getEnv("RSS_SEND_UPDATES", "false")); err != nil {
+		log.Fatal("could not convert RSS_SEND_UPDATES", err)
+	}
+	DeliveryRetryPolicy = defaultRetryPolicy
+	if DeliveryRetryPolicy.MaxAttempts, err = strconv.Atoi(getEnv("DELIVERY_MAX_ATTEMPTS", strconv.Itoa(defaultRetryPolicy.MaxAttempts))); err != nil {
+		log.Fatal("could not convert DELIVERY_MAX_ATTEMPTS", err)
+	}
+	if DeliveryRetryPolicy.BaseDelay, err = time.ParseDuration(getEnv("DELIVERY_BACKOFF_BASE", defaultRetryPolicy.BaseDelay.String())); err != nil {
+		log.Fatal("could not convert DELIVERY_BACKOFF_BASE", err)
+	}
+	if DeliveryRetryPolicy.MaxDelay, err = time.ParseDuration(getEnv("DELIVERY_BACKOFF_MAX", defaultRetryPolicy.MaxDelay.String())); err != nil {
+		log.Fatal("could not convert DELIVERY_BACKOFF_MAX", err)
+	}
+	if OutboxPollingRate, err = time.ParseDuration(getEnv("OUTBOX_POLLING_RATE", "1m")); err != nil {
+		log.Fatal("could not convert OUTBOX_POLLING_RATE", err)
+	}
+	if OutboxLease, err = time.ParseDuration(getEnv("OUTBOX_LEASE", "5m")); err != nil {
+		log.Fatal("could not convert OUTBOX_LEASE", err)
+	}
+	if OutboxMaxAttempts, err = strconv.Atoi(getEnv("OUTBOX_MAX_ATTEMPTS", "5")); err != nil {
+		log.Fatal("could not convert OUTBOX_MAX_ATTEMPTS", err)
+	}
+	if OutboxRetention, err = time.ParseDuration(getEnv("OUTBOX_RETENTION", "168h")); err != nil {
+		log.Fatal("could not convert OUTBOX_RETENTION", err)
+	}
+	if DeliveryHistoryRetention, err = time.ParseDuration(getEnv("DELIVERY_HISTORY_RETENTION", "720h")); err != nil {
+		log.Fatal("could not convert DELIVERY_HISTORY_RETENTION", err)
+	}
+	if WebhookFailureThreshold, err = strconv.Atoi(getEnv("WEBHOOK_FAILURE_THRESHOLD", "5")); err != nil {
+		log.Fatal("could not convert WEBHOOK_FAILURE_THRESHOLD", err)
+	}
+	if WebhookFailureWindow, err = time.ParseDuration(getEnv("WEBHOOK_FAILURE_WINDOW", "24h")); err != nil {
+		log.Fatal("could not convert WEBHOOK_FAILURE_WINDOW", err)
+	}
+	if WebhookRetention, err = time.ParseDuration(getEnv("WEBHOOK_RETENTION", "720h")); err != nil {
+		log.Fatal("could not convert WEBHOOK_RETENTION", err)
+	}
+	if RateLimitRps, err = strconv.ParseFloat(getEnv("RATE_LIMIT_RPS", "1"), 64); err != nil || RateLimitRps < 0 {
+		log.Fatal("could not convert RATE_LIMIT_RPS", err)
+	}
+	if RateLimitBurst, err = strconv.Atoi(getEnv("RATE_LIMIT_BURST", "5")); err != nil || RateLimitBurst < 1 {
+		log.Fatal("could not convert RATE_LIMIT_BURST", err)
+	}
+	if TrustedProxies, err = parseTrustedProxies(getEnv("TRUSTED_PROXIES", "")); err != nil {
+		log.Fatal("could not convert TRUSTED_PROXIES", err)
+	}
 	TwitterToken = getEnv("TWITTER_TOKEN", "undefined")
+	ServerlessSenderUrl = getEnv("SERVERLESS_SENDER_URL", "")
 	PostgresUrl = getEnv("POSTGRES_URL", "")
 	if PostgresUrl == "" {
 		log.Fatal("POSTGRES_URL is not defined.")
 	}
+	var poolSize int
+	if poolSize, err = strconv.Atoi(getEnv("POSTGRES_POOL_SIZE", "10")); err != nil || poolSize < 1 {
+		log.Fatal("could not convert POSTGRES_POOL_SIZE", err)
+	}
+	PostgresPoolSize = int32(poolSize)
 	ServerTz = getEnv("SERVER_TZ", "Europe/Berlin")
 }
 
@@ -51,7 +127,16 @@ func TruncateText(s string, max int) string {
 	if max > len(s) {
 		return s
 	}
-	return s[:strings.LastIndex(s[:max], " ")] + " ..."
+	cut := strings.LastIndex(s[:max], " ")
+	if cut < 0 {
+		cut = max
+	}
+	return s[:cut] + " ..."
+}
+
+// isEmptyText reports whether an optional text is missing or empty.
+func isEmptyText(text *string) bool {
+	return text == nil || *text == ""
 }
 
 func getEnv(key, fallback string) string {
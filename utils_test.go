@@ -68,3 +68,7 @@ func TestSetSlice(t *testing.T) {
 	s.Remove("foo")
 	assert.ElementsMatch(t, []string{"bar"}, s.Slice())
 }
+
+func TestTruncateTextWithoutSpaces(t *testing.T) {
+	assert.Equal(t, "abcde ...", TruncateText("abcdefghij", 5))
+}